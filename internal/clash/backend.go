@@ -0,0 +1,38 @@
+package clash
+
+import "context"
+
+// Backend is the set of controller operations the TUI depends on.
+// Client talks to a real controller over HTTP, MemoryBackend keeps
+// everything in memory for mock mode and tests.
+type Backend interface {
+	GetVersionContext(ctx context.Context) (*Version, error)
+	GetProxiesContext(ctx context.Context) (*ProxiesResponse, error)
+	SelectProxyContext(ctx context.Context, groupName, proxyName string) error
+	ResetFixedProxyContext(ctx context.Context, groupName string) error
+	ProxyDelayContext(ctx context.Context, proxyName string, opts DelayOptions) (DelayResult, error)
+	GroupDelayContext(ctx context.Context, groupName string, opts DelayOptions) (map[string]int, error)
+	GetConnectionsContext(ctx context.Context) (*ConnectionsResponse, error)
+	CloseConnectionContext(ctx context.Context, id string) error
+	CloseAllConnectionsContext(ctx context.Context) error
+	GetRulesContext(ctx context.Context) (*RulesResponse, error)
+	GetProxyProvidersContext(ctx context.Context) (*ProxyProvidersResponse, error)
+	UpdateProxyProviderContext(ctx context.Context, name string) error
+	HealthCheckProxyProviderContext(ctx context.Context, name string) error
+	GetRuleProvidersContext(ctx context.Context) (*RuleProvidersResponse, error)
+	UpdateRuleProviderContext(ctx context.Context, name string) error
+	GetConfigContext(ctx context.Context) (*Config, error)
+	PatchConfigContext(ctx context.Context, patch ConfigPatch) error
+	ReloadConfigContext(ctx context.Context, req ReloadConfigRequest) error
+	RestartContext(ctx context.Context) error
+	FlushFakeIPCacheContext(ctx context.Context) error
+	FlushDNSCacheContext(ctx context.Context) error
+	StreamTrafficContext(ctx context.Context) (*Stream[Traffic], error)
+	StreamMemoryContext(ctx context.Context) (*Stream[MemoryUsage], error)
+	StreamLogsContext(ctx context.Context, level string) (*Stream[LogEntry], error)
+}
+
+var (
+	_ Backend = (*Client)(nil)
+	_ Backend = (*MemoryBackend)(nil)
+)
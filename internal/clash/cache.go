@@ -0,0 +1,37 @@
+package clash
+
+import "context"
+
+const cachePath = "cache"
+
+// FlushFakeIPCache drops the fake-ip mappings (Clash.Meta/Mihomo), so
+// domains get fresh addresses after switching nodes.
+func (c *Client) FlushFakeIPCache() error {
+	return c.FlushFakeIPCacheContext(context.Background())
+}
+
+func (c *Client) FlushFakeIPCacheContext(ctx context.Context) error {
+	return c.flushCache(ctx, "fakeip")
+}
+
+// FlushDNSCache drops the resolver's cached answers (Clash.Meta/Mihomo).
+func (c *Client) FlushDNSCache() error {
+	return c.FlushDNSCacheContext(context.Background())
+}
+
+func (c *Client) FlushDNSCacheContext(ctx context.Context) error {
+	return c.flushCache(ctx, "dns")
+}
+
+func (c *Client) flushCache(ctx context.Context, cache string) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.do(ctx, "POST", c.endpoint(cachePath, cache, "flush"), nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
@@ -2,30 +2,48 @@ package clash
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
-	"sync"
+	"net/url"
+	"strings"
+	"time"
 )
 
 const (
 	defaultClashURL = "http://127.0.0.1:9090"
-	proxiesPath     = "/proxies"
+	proxiesPath     = "proxies"
+	groupPath       = "group"
 )
 
-var (
-	mockMode  = os.Getenv("MOCK_CLASH") == "1"
-	apiSecret = os.Getenv("MIHOMO_SECRET")
-)
+// DefaultTimeout bounds every request that has no earlier deadline.
+const DefaultTimeout = 10 * time.Second
+
+// Options configures a Client. The zero value talks to the default
+// controller address without authentication.
+type Options struct {
+	BaseURL string // controller address; "host:port" is treated as http, unix:///path uses a socket
+	Secret  string // API secret sent as a bearer token
+	TLS     TLSOptions
+	Timeout time.Duration // per-request timeout, DefaultTimeout when zero
 
+	// WebSocket makes streaming endpoints such as /traffic use WebSocket
+	// instead of chunked HTTP, e.g. behind proxies that buffer responses.
+	WebSocket bool
+}
+
+// Client is the HTTP implementation of Backend, talking to the controller's
+// RESTful API.
 type Client struct {
-	baseURL       string
-	secret        string
-	httpClient    *http.Client
-	mockProxies   map[string]Proxy
-	mockProxiesMu sync.RWMutex
+	address    string // controller address as configured, for error messages
+	baseURL    string
+	secret     string
+	timeout    time.Duration
+	webSocket  bool
+	httpClient *http.Client
 }
 
 type Proxy struct {
@@ -48,15 +66,61 @@ type ProxiesResponse struct {
 	Proxies map[string]Proxy `json:"proxies"`
 }
 
-func NewClient(baseURL string) *Client {
+func NewClient(opts Options) (*Client, error) {
+	baseURL := opts.BaseURL
 	if baseURL == "" {
 		baseURL = defaultClashURL
 	}
-	return &Client{
-		baseURL:    baseURL,
-		secret:     apiSecret,
-		httpClient: &http.Client{},
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "http://" + baseURL
+	}
+	address := baseURL
+
+	httpClient := &http.Client{}
+	if socketPath, ok := splitUnixAddress(baseURL); ok {
+		httpClient.Transport = newUnixTransport(socketPath)
+		baseURL = unixHostURL
+	} else if !opts.TLS.isZero() {
+		tlsConfig, err := newTLSConfig(opts.TLS)
+		if err != nil {
+			return nil, err
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		httpClient.Transport = transport
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
 	}
+
+	return &Client{
+		address:    address,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		secret:     opts.Secret,
+		timeout:    timeout,
+		webSocket:  opts.WebSocket,
+		httpClient: httpClient,
+	}, nil
+}
+
+// endpoint builds a request URL from path segments. Each segment is
+// percent-encoded on its own, so names containing "/", "#", "?", spaces or
+// emoji stay a single path segment.
+func (c *Client) endpoint(segments ...string) string {
+	var b strings.Builder
+	b.WriteString(c.baseURL)
+	for _, seg := range segments {
+		b.WriteByte('/')
+		b.WriteString(url.PathEscape(seg))
+	}
+	return b.String()
+}
+
+// withTimeout applies the per-request timeout on top of ctx.
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, c.timeout)
 }
 
 func (c *Client) addAuthHeader(req *http.Request) {
@@ -65,194 +129,118 @@ func (c *Client) addAuthHeader(req *http.Request) {
 	}
 }
 
-func (c *Client) GetProxies() (*ProxiesResponse, error) {
-	if mockMode {
-		c.mockProxiesMu.RLock()
-		if c.mockProxies != nil {
-			defer c.mockProxiesMu.RUnlock()
-			return &ProxiesResponse{Proxies: c.mockProxies}, nil
-		}
-		c.mockProxiesMu.RUnlock()
-
-		// Initialize mock data for testing
-		c.mockProxiesMu.Lock()
-		defer c.mockProxiesMu.Unlock()
-		c.mockProxies = make(map[string]Proxy)
-		c.mockProxies["Proxy Group A"] = Proxy{
-			Name: "Proxy Group A",
-			Type: "Selector",
-			Now:  "Proxy-1",
-			All:  []string{"Proxy-1", "Proxy-2", "Proxy-3", "Proxy-4", "Proxy-5", "Proxy-6", "Proxy-7"},
-		}
-		c.mockProxies["Proxy Group B"] = Proxy{
-			Name: "Proxy Group B",
-			Type: "URLTest",
-			Now:  "Auto-2",
-			All:  []string{"Auto-1", "Auto-2", "Auto-3", "Auto-4", "Auto-5", "Auto-6"},
-		}
-		c.mockProxies["Proxy Group C"] = Proxy{
-			Name: "Proxy Group C",
-			Type: "Selector",
-			Now:  "Direct-1",
-			All:  []string{"Direct-1", "Direct-2", "Direct-3", "Direct-4", "Direct-5", "Direct-6", "Direct-7", "Direct-8"},
+// do sends a request with an optional JSON body. Transport failures become
+// *UnreachableError and non-2xx answers *UnauthorizedError or *StatusError.
+// The caller must close the response body.
+func (c *Client) do(ctx context.Context, method, url string, payload any) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal payload: %w", err)
 		}
-		return &ProxiesResponse{Proxies: c.mockProxies}, nil
+		body = bytes.NewReader(b)
 	}
 
-	url := c.baseURL + proxiesPath
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 	c.addAuthHeader(req)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get proxies: %w", err)
+		return nil, c.transportError(ctx, err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
-	}
-
-	var result ProxiesResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		return nil, newStatusError(resp)
 	}
-
-	return &result, nil
+	return resp, nil
 }
 
-func (c *Client) SelectProxy(groupName, proxyName string) error {
-	if mockMode {
-		c.mockProxiesMu.Lock()
-		defer c.mockProxiesMu.Unlock()
-
-		if proxy, ok := c.mockProxies[groupName]; ok {
-			for _, p := range proxy.All {
-				if p == proxyName {
-					proxy.Now = proxyName
-					c.mockProxies[groupName] = proxy
-					return nil
-				}
-			}
-			return fmt.Errorf("proxy %s not found in group %s", proxyName, groupName)
-		}
-		return fmt.Errorf("group %s not found", groupName)
-	}
-
-	url := c.baseURL + proxiesPath + "/" + groupName
-
-	payload := map[string]string{
-		"name": proxyName,
+// decodeJSON decodes a response body into out and closes it.
+func decodeJSON(resp *http.Response, out any) error {
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return &DecodeError{Err: err}
 	}
+	return nil
+}
 
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %w", err)
-	}
+func (c *Client) GetProxies() (*ProxiesResponse, error) {
+	return c.GetProxiesContext(context.Background())
+}
 
-	req, err := http.NewRequest("PUT", url, bytes.NewBuffer(body))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-	c.addAuthHeader(req)
+func (c *Client) GetProxiesContext(ctx context.Context) (*ProxiesResponse, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(ctx, "GET", c.endpoint(proxiesPath), nil)
 	if err != nil {
-		return fmt.Errorf("failed to select proxy: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+	var result ProxiesResponse
+	if err := decodeJSON(resp, &result); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return &result, nil
 }
 
-func (c *Client) TestDelay(groupName, proxyName string, testURL string) (int, error) {
-	url := c.baseURL + proxiesPath + "/" + groupName + "/delay"
+func (c *Client) SelectProxy(groupName, proxyName string) error {
+	return c.SelectProxyContext(context.Background(), groupName, proxyName)
+}
 
-	if testURL == "" {
-		testURL = "http://www.gstatic.com/generate_204"
-	}
+func (c *Client) SelectProxyContext(ctx context.Context, groupName, proxyName string) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
 
 	payload := map[string]string{
-		"url":     testURL,
-		"timeout": "5000",
+		"name": proxyName,
 	}
 
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return 0, fmt.Errorf("failed to marshal payload: %w", err)
+	resp, err := c.do(ctx, "PUT", c.endpoint(proxiesPath, groupName), payload)
+	if StatusCode(err) == http.StatusNotFound {
+		return &GroupNotFoundError{Group: groupName}
 	}
-
-	req, err := http.NewRequest("GET", url, bytes.NewBuffer(body))
-	if err != nil {
-		return 0, fmt.Errorf("failed to create request: %w", err)
+	// 400 also covers "Must be a Selector" and "Body invalid", only
+	// "Selector update error: proxy not exist" is about the proxy
+	var se *StatusError
+	if errors.As(err, &se) && se.StatusCode == http.StatusBadRequest && strings.Contains(se.Message, "not exist") {
+		return &ProxyNotInGroupError{Group: groupName, Proxy: proxyName}
 	}
-	req.Header.Set("Content-Type", "application/json")
-	c.addAuthHeader(req)
-
-	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return 0, fmt.Errorf("failed to test delay: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return 0, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
-	}
-
-	var result map[string]int
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return 0, fmt.Errorf("failed to decode response: %w", err)
+		return err
 	}
+	resp.Body.Close()
 
-	return result["delay"], nil
+	return nil
 }
 
 // ResetFixedProxy clears the 'fixed' field on a URLTest group to restore auto-selection.
 // Uses DELETE method on the proxy group endpoint.
 func (c *Client) ResetFixedProxy(groupName string) error {
-	if mockMode {
-		c.mockProxiesMu.Lock()
-		defer c.mockProxiesMu.Unlock()
-
-		if proxy, ok := c.mockProxies[groupName]; ok {
-			// In mock mode, simulate clearing the fixed field
-			proxy.Fixed = ""
-			c.mockProxies[groupName] = proxy
-			return nil
-		}
-		return fmt.Errorf("group %s not found", groupName)
-	}
+	return c.ResetFixedProxyContext(context.Background(), groupName)
+}
 
-	url := c.baseURL + proxiesPath + "/" + groupName
+func (c *Client) ResetFixedProxyContext(ctx context.Context, groupName string) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
 
-	req, err := http.NewRequest("DELETE", url, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+	// DELETE returns 204 No Content on success
+	resp, err := c.do(ctx, "DELETE", c.endpoint(proxiesPath, groupName), nil)
+	if StatusCode(err) == http.StatusNotFound {
+		return &GroupNotFoundError{Group: groupName}
 	}
-	c.addAuthHeader(req)
-
-	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to reset fixed proxy: %w", err)
+		return err
 	}
-	defer resp.Body.Close()
+	resp.Body.Close()
 
-	// DELETE returns 204 No Content on success
-	if resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusOK {
-		return nil
-	}
-
-	body, _ := io.ReadAll(resp.Body)
-	return fmt.Errorf("failed to reset fixed proxy (status %d): %s", resp.StatusCode, string(body))
+	return nil
 }
@@ -0,0 +1,256 @@
+package clash
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUnixSocketController(t *testing.T) {
+	dir, err := os.MkdirTemp("", "pct")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socketPath := filepath.Join(dir, "mihomo.sock")
+
+	ln, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Skipf("unix sockets not available: %v", err)
+	}
+
+	var selected string
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/proxies":
+			json.NewEncoder(w).Encode(ProxiesResponse{Proxies: map[string]Proxy{
+				"GLOBAL": {Name: "GLOBAL", Type: "Selector", Now: "DIRECT", All: []string{"DIRECT"}},
+			}})
+		case r.Method == http.MethodPut && r.URL.Path == "/proxies/GLOBAL":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			selected = body["name"]
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	srv.Listener = ln
+	srv.Start()
+	defer srv.Close()
+
+	c, err := NewClient(Options{BaseURL: "unix://" + socketPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proxies, err := c.GetProxies()
+	if err != nil {
+		t.Fatalf("GetProxies over unix socket failed: %v", err)
+	}
+	if _, ok := proxies.Proxies["GLOBAL"]; !ok {
+		t.Errorf("Expected GLOBAL group in response, got %v", proxies.Proxies)
+	}
+
+	if err := c.SelectProxy("GLOBAL", "DIRECT"); err != nil {
+		t.Fatalf("SelectProxy over unix socket failed: %v", err)
+	}
+	if selected != "DIRECT" {
+		t.Errorf("Expected server to receive DIRECT, got %q", selected)
+	}
+}
+
+func TestProxyDelay(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.Method != http.MethodGet || q.Get("url") != "http://example.com/204" || q.Get("timeout") != "3000" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+		}
+		switch r.URL.Path {
+		case "/proxies/fast/delay":
+			if q.Get("expected") != "204" {
+				t.Errorf("Expected 'expected=204', got %q", q.Get("expected"))
+			}
+			w.Write([]byte(`{"delay": 42}`))
+		case "/proxies/slow/delay":
+			w.WriteHeader(http.StatusGatewayTimeout)
+			w.Write([]byte(`{"message": "Timeout"}`))
+		case "/proxies/dead/delay":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"message": "An error occurred in the delay test"}`))
+		case "/proxies/garbled/delay":
+			w.Write([]byte(`<html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(Options{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		proxy    string
+		expected string
+		status   DelayStatus
+		delay    int
+	}{
+		{"fast", "204", DelayOK, 42},
+		{"slow", "", DelayTimeout, 0},
+		{"dead", "", DelayUnreachable, 0},
+		{"garbled", "", DelayMalformed, 0},
+	}
+	for _, tt := range tests {
+		opts := DelayOptions{URL: "http://example.com/204", Timeout: 3 * time.Second, Expected: tt.expected}
+		res, err := c.ProxyDelay(tt.proxy, opts)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.proxy, err)
+			continue
+		}
+		if res.Status != tt.status || res.Delay != tt.delay {
+			t.Errorf("%s: expected %v/%d, got %v/%d", tt.proxy, tt.status, tt.delay, res.Status, res.Delay)
+		}
+	}
+
+	if _, err := c.ProxyDelay("missing", DelayOptions{URL: "http://example.com/204", Timeout: 3 * time.Second}); err == nil {
+		t.Errorf("Expected an error for an unknown proxy")
+	}
+}
+
+func TestSelectProxyErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		switch r.URL.Path {
+		case "/proxies/Selector":
+			w.Write([]byte(`{"message": "Selector update error: proxy not exist"}`))
+		case "/proxies/Auto":
+			w.Write([]byte(`{"message": "Must be a Selector"}`))
+		default:
+			w.Write([]byte(`{"message": "Body invalid"}`))
+		}
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(Options{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var notInGroup *ProxyNotInGroupError
+	if err := c.SelectProxy("Selector", "Gone"); !errors.As(err, &notInGroup) {
+		t.Errorf("Expected ProxyNotInGroupError, got %v", err)
+	}
+	// Other 400 answers are not about the proxy
+	for _, group := range []string{"Auto", "Other"} {
+		err := c.SelectProxy(group, "Proxy-1")
+		var status *StatusError
+		if !errors.As(err, &status) || errors.As(err, &notInGroup) {
+			t.Errorf("%s: expected a plain StatusError, got %v", group, err)
+		}
+	}
+}
+
+func TestVersionCapabilities(t *testing.T) {
+	tests := []struct {
+		version     Version
+		healthcheck bool
+		unixSocket  bool
+	}{
+		{Version{Version: "v1.19.0", Meta: true}, true, true},
+		{Version{Version: "alpha-0c0a8a0", Meta: true}, true, true},
+		{Version{Version: "v1.17.0", Meta: true}, true, false},
+		{Version{Version: "2023.08.17", Premium: true}, true, false},
+		{Version{Version: "v1.18.0"}, true, false},
+		{Version{Version: "v0.17.1"}, false, false},
+	}
+	for _, tt := range tests {
+		caps := tt.version.Capabilities()
+		if caps.ProviderHealthcheck != tt.healthcheck || caps.UnixSocket != tt.unixSocket {
+			t.Errorf("%s: got %+v", tt.version, caps)
+		}
+	}
+}
+
+func TestPathEscaping(t *testing.T) {
+	names := []string{
+		"Proxy Group A",
+		"🇯🇵 Japan/Tokyo #2",
+		"What? 100%",
+	}
+
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Method+" "+r.URL.EscapedPath())
+		switch r.Method {
+		case http.MethodPut, http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Write([]byte(`{"delay": 1}`))
+		}
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(Options{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range names {
+		got = nil
+		if err := c.SelectProxy(name, "DIRECT"); err != nil {
+			t.Fatalf("SelectProxy(%q): %v", name, err)
+		}
+		if err := c.ResetFixedProxy(name); err != nil {
+			t.Fatalf("ResetFixedProxy(%q): %v", name, err)
+		}
+		if _, err := c.ProxyDelay(name, DelayOptions{}); err != nil {
+			t.Fatalf("ProxyDelay(%q): %v", name, err)
+		}
+		if _, err := c.GroupDelay(name, DelayOptions{}); err != nil {
+			t.Fatalf("GroupDelay(%q): %v", name, err)
+		}
+
+		seg := url.PathEscape(name)
+		want := []string{
+			"PUT /proxies/" + seg,
+			"DELETE /proxies/" + seg,
+			"GET /proxies/" + seg + "/delay",
+			"GET /group/" + seg + "/delay",
+		}
+		for i := range want {
+			if i >= len(got) || got[i] != want[i] {
+				t.Errorf("%q: expected request %q, got %q", name, want[i], got)
+			}
+		}
+	}
+}
+
+func TestEndpointSegments(t *testing.T) {
+	c, err := NewClient(Options{BaseURL: "http://127.0.0.1:9090/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := url.Parse(c.endpoint("proxies", "🇯🇵 Japan/Tokyo #2", "delay"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Fragment != "" || u.RawQuery != "" {
+		t.Errorf("Name leaked into fragment or query: %s", u)
+	}
+	if u.Path != "/proxies/🇯🇵 Japan/Tokyo #2/delay" {
+		t.Errorf("Unexpected decoded path %q", u.Path)
+	}
+	if u.EscapedPath() != "/proxies/%F0%9F%87%AF%F0%9F%87%B5%20Japan%2FTokyo%20%232/delay" {
+		t.Errorf("Unexpected escaped path %q", u.EscapedPath())
+	}
+}
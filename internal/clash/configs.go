@@ -0,0 +1,222 @@
+package clash
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	configsPath = "configs"
+	restartPath = "restart"
+)
+
+// Proxy modes of the core.
+const (
+	ModeRule   = "rule"
+	ModeGlobal = "global"
+	ModeDirect = "direct"
+)
+
+// Modes lists the proxy modes in the order the TUI cycles through them.
+var Modes = []string{ModeRule, ModeGlobal, ModeDirect}
+
+// LogSilent turns core logging off. It is a config log level only, /logs
+// does not accept it.
+const LogSilent = "silent"
+
+// LogLevels lists the log-level values of the config, from the most verbose.
+var LogLevels = []string{LogDebug, LogInfo, LogWarning, LogError, LogSilent}
+
+// Config is the running configuration reported by /configs. Only the
+// fields the TUI reads are decoded.
+type Config struct {
+	Port        int    `json:"port"`
+	SocksPort   int    `json:"socks-port"`
+	RedirPort   int    `json:"redir-port"`
+	TProxyPort  int    `json:"tproxy-port"`
+	MixedPort   int    `json:"mixed-port"`
+	AllowLan    bool   `json:"allow-lan"`
+	BindAddress string `json:"bind-address"`
+	Mode        string `json:"mode"` // rule, global or direct; Clash Premium may capitalize it
+	LogLevel    string `json:"log-level"`
+	IPv6        bool   `json:"ipv6"`
+	// Tun is only reported by Clash.Meta/Mihomo.
+	Tun *TunConfig `json:"tun,omitempty"`
+}
+
+type TunConfig struct {
+	Enable bool   `json:"enable"`
+	Stack  string `json:"stack,omitempty"`
+	Device string `json:"device,omitempty"`
+}
+
+// ConfigPatch is a partial update for PATCH /configs. Nil fields are left
+// unchanged.
+type ConfigPatch struct {
+	Mode      *string   `json:"mode,omitempty"`
+	LogLevel  *string   `json:"log-level,omitempty"`
+	AllowLan  *bool     `json:"allow-lan,omitempty"`
+	IPv6      *bool     `json:"ipv6,omitempty"`
+	MixedPort *int      `json:"mixed-port,omitempty"`
+	SocksPort *int      `json:"socks-port,omitempty"`
+	Tun       *TunPatch `json:"tun,omitempty"`
+}
+
+type TunPatch struct {
+	Enable *bool `json:"enable,omitempty"`
+}
+
+// Apply copies the fields set in p into c, the way the core handles a
+// PATCH. Unknown modes or log levels and out of range ports are rejected
+// without changing anything.
+func (c *Config) Apply(p ConfigPatch) error {
+	next := *c
+	if p.Mode != nil {
+		mode := strings.ToLower(*p.Mode)
+		if !slices.Contains(Modes, mode) {
+			return fmt.Errorf("invalid mode %q", *p.Mode)
+		}
+		next.Mode = mode
+	}
+	if p.LogLevel != nil {
+		if !slices.Contains(LogLevels, *p.LogLevel) {
+			return fmt.Errorf("invalid log level %q", *p.LogLevel)
+		}
+		next.LogLevel = *p.LogLevel
+	}
+	if p.AllowLan != nil {
+		next.AllowLan = *p.AllowLan
+	}
+	if p.IPv6 != nil {
+		next.IPv6 = *p.IPv6
+	}
+	if p.MixedPort != nil {
+		if !ValidPort(*p.MixedPort) {
+			return fmt.Errorf("invalid mixed-port %d", *p.MixedPort)
+		}
+		next.MixedPort = *p.MixedPort
+	}
+	if p.SocksPort != nil {
+		if !ValidPort(*p.SocksPort) {
+			return fmt.Errorf("invalid socks-port %d", *p.SocksPort)
+		}
+		next.SocksPort = *p.SocksPort
+	}
+	if p.Tun != nil && p.Tun.Enable != nil {
+		tun := TunConfig{}
+		if next.Tun != nil {
+			tun = *next.Tun
+		}
+		tun.Enable = *p.Tun.Enable
+		next.Tun = &tun
+	}
+	*c = next
+	return nil
+}
+
+// ValidPort reports whether port can be set as a listener port, 0 turning
+// the listener off.
+func ValidPort(port int) bool {
+	return port >= 0 && port <= 65535
+}
+
+func (c *Client) GetConfig() (*Config, error) {
+	return c.GetConfigContext(context.Background())
+}
+
+func (c *Client) GetConfigContext(ctx context.Context) (*Config, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.do(ctx, "GET", c.endpoint(configsPath), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result Config
+	if err := decodeJSON(resp, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// PatchConfig changes some settings of the running core. The change is not
+// written back to the config file.
+func (c *Client) PatchConfig(patch ConfigPatch) error {
+	return c.PatchConfigContext(context.Background(), patch)
+}
+
+func (c *Client) PatchConfigContext(ctx context.Context, patch ConfigPatch) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.do(ctx, "PATCH", c.endpoint(configsPath), patch)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// ReloadConfigRequest selects what PUT /configs loads: the file at Path,
+// the YAML in Payload, or the core's own config file when both are empty.
+type ReloadConfigRequest struct {
+	Path    string `json:"path,omitempty"` // absolute path on the core's host
+	Payload string `json:"payload,omitempty"`
+}
+
+// ReloadConfig replaces the running config. Runtime changes made with
+// PatchConfig are lost.
+func (c *Client) ReloadConfig(req ReloadConfigRequest) error {
+	return c.ReloadConfigContext(context.Background(), req)
+}
+
+// ReloadConfigContext sends the reload with force=true, so listeners and
+// providers are rebuilt even when the file did not change.
+func (c *Client) ReloadConfigContext(ctx context.Context, req ReloadConfigRequest) error {
+	// Loading may fetch providers before the core answers
+	ctx, cancel := context.WithTimeout(ctx, c.timeout+DefaultDelayTimeout)
+	defer cancel()
+
+	resp, err := c.do(ctx, "PUT", c.endpoint(configsPath)+"?force=true", req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// Restart makes the core re-execute itself (Clash.Meta/Mihomo). It answers
+// before restarting, so the controller is briefly unreachable afterwards.
+func (c *Client) Restart() error {
+	return c.RestartContext(context.Background())
+}
+
+func (c *Client) RestartContext(ctx context.Context) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.do(ctx, "POST", c.endpoint(restartPath), nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// MockConfig returns the configuration reported in mock mode.
+func MockConfig() Config {
+	return Config{
+		MixedPort: 7890,
+		Mode:      ModeRule,
+		LogLevel:  LogInfo,
+		IPv6:      true,
+		Tun:       &TunConfig{Stack: "mixed", Device: "Meta"},
+	}
+}
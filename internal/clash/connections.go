@@ -0,0 +1,101 @@
+package clash
+
+import (
+	"context"
+	"time"
+)
+
+const connectionsPath = "connections"
+
+// ConnectionMetadata describes the endpoints of a tracked connection.
+type ConnectionMetadata struct {
+	Network         string `json:"network"` // tcp or udp
+	Type            string `json:"type"`    // inbound type, e.g. HTTP, Socks5, Tun
+	SourceIP        string `json:"sourceIP"`
+	SourcePort      string `json:"sourcePort"`
+	DestinationIP   string `json:"destinationIP"`
+	DestinationPort string `json:"destinationPort"`
+	Host            string `json:"host"`
+	DNSMode         string `json:"dnsMode"`
+	Process         string `json:"process"`
+	ProcessPath     string `json:"processPath"`
+	SpecialProxy    string `json:"specialProxy"`
+}
+
+// Connection is an active connection tracked by the core.
+type Connection struct {
+	ID          string             `json:"id"`
+	Metadata    ConnectionMetadata `json:"metadata"`
+	Upload      int64              `json:"upload"`
+	Download    int64              `json:"download"`
+	Start       time.Time          `json:"start"`
+	Chains      []string           `json:"chains"` // outermost proxy first, matched group last
+	Rule        string             `json:"rule"`
+	RulePayload string             `json:"rulePayload"`
+}
+
+// ConnectionsResponse is the answer of GET /connections.
+type ConnectionsResponse struct {
+	DownloadTotal int64        `json:"downloadTotal"`
+	UploadTotal   int64        `json:"uploadTotal"`
+	Connections   []Connection `json:"connections"`
+	Memory        int64        `json:"memory"` // Mihomo only
+}
+
+func (c *Client) GetConnections() (*ConnectionsResponse, error) {
+	return c.GetConnectionsContext(context.Background())
+}
+
+func (c *Client) GetConnectionsContext(ctx context.Context) (*ConnectionsResponse, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.do(ctx, "GET", c.endpoint(connectionsPath), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result ConnectionsResponse
+	if err := decodeJSON(resp, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// CloseConnection closes one connection via DELETE /connections/{id}. The
+// core answers 204 even when the connection is already gone.
+func (c *Client) CloseConnection(id string) error {
+	return c.CloseConnectionContext(context.Background(), id)
+}
+
+func (c *Client) CloseConnectionContext(ctx context.Context, id string) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.do(ctx, "DELETE", c.endpoint(connectionsPath, id), nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// CloseAllConnections closes every connection via DELETE /connections.
+func (c *Client) CloseAllConnections() error {
+	return c.CloseAllConnectionsContext(context.Background())
+}
+
+func (c *Client) CloseAllConnectionsContext(ctx context.Context) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.do(ctx, "DELETE", c.endpoint(connectionsPath), nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
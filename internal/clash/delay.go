@@ -0,0 +1,155 @@
+package clash
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultDelayTestURL = "http://www.gstatic.com/generate_204"
+	DefaultDelayTimeout = 5 * time.Second
+)
+
+// DelayOptions configures a delay test. Zero values use the defaults.
+type DelayOptions struct {
+	URL     string        // URL fetched through the proxy
+	Timeout time.Duration // how long the core waits before reporting a timeout
+	// Expected lists acceptable HTTP status codes in Mihomo syntax,
+	// e.g. "204" or "200/204" or "200-299". Empty accepts any status.
+	Expected string
+}
+
+// DelayStatus classifies the outcome of a delay test.
+type DelayStatus int
+
+const (
+	DelayOK          DelayStatus = iota
+	DelayTimeout                 // core gave up waiting (504)
+	DelayUnreachable             // proxy failed the test (503)
+	DelayMalformed               // response could not be understood
+)
+
+func (s DelayStatus) String() string {
+	switch s {
+	case DelayOK:
+		return "ok"
+	case DelayTimeout:
+		return "timeout"
+	case DelayUnreachable:
+		return "unreachable"
+	case DelayMalformed:
+		return "malformed response"
+	}
+	return "unknown"
+}
+
+// DelayResult is the outcome of a single proxy delay test.
+type DelayResult struct {
+	Delay   int // milliseconds, only meaningful when Status is DelayOK
+	Status  DelayStatus
+	Message string // message returned by the core for failed tests
+}
+
+func (o DelayOptions) withDefaults() DelayOptions {
+	if o.URL == "" {
+		o.URL = DefaultDelayTestURL
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultDelayTimeout
+	}
+	return o
+}
+
+func (o DelayOptions) query() url.Values {
+	q := url.Values{}
+	q.Set("url", o.URL)
+	q.Set("timeout", strconv.FormatInt(o.Timeout.Milliseconds(), 10))
+	if o.Expected != "" {
+		q.Set("expected", o.Expected)
+	}
+	return q
+}
+
+// ProxyDelay asks the core to test a single proxy via
+// GET /proxies/{name}/delay.
+func (c *Client) ProxyDelay(proxyName string, opts DelayOptions) (DelayResult, error) {
+	return c.ProxyDelayContext(context.Background(), proxyName, opts)
+}
+
+// ProxyDelayContext is ProxyDelay with a context. Failed tests are reported
+// through DelayResult.Status; the error is reserved for requests that could
+// not be made or were rejected (bad secret, unknown proxy, bad options).
+func (c *Client) ProxyDelayContext(ctx context.Context, proxyName string, opts DelayOptions) (DelayResult, error) {
+	opts = opts.withDefaults()
+
+	// The core may legitimately take the whole test timeout to answer
+	ctx, cancel := context.WithTimeout(ctx, c.timeout+opts.Timeout)
+	defer cancel()
+
+	u := c.endpoint(proxiesPath, proxyName, "delay") + "?" + opts.query().Encode()
+	resp, err := c.do(ctx, "GET", u, nil)
+	var se *StatusError
+	if errors.As(err, &se) {
+		switch se.StatusCode {
+		case http.StatusGatewayTimeout:
+			return DelayResult{Status: DelayTimeout, Message: se.Message}, nil
+		case http.StatusServiceUnavailable:
+			return DelayResult{Status: DelayUnreachable, Message: se.Message}, nil
+		case http.StatusNotFound:
+			return DelayResult{}, &ProxyNotFoundError{Proxy: proxyName}
+		}
+	}
+	if err != nil {
+		return DelayResult{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return DelayResult{}, c.transportError(ctx, err)
+	}
+
+	var result struct {
+		Delay *int `json:"delay"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil || result.Delay == nil {
+		return DelayResult{Status: DelayMalformed, Message: string(body)}, nil
+	}
+	return DelayResult{Delay: *result.Delay}, nil
+}
+
+// GroupDelay tests every member of a group at once via
+// GET /group/{name}/delay and returns the delay of each proxy that passed.
+// Members missing from the result failed the test.
+func (c *Client) GroupDelay(groupName string, opts DelayOptions) (map[string]int, error) {
+	return c.GroupDelayContext(context.Background(), groupName, opts)
+}
+
+func (c *Client) GroupDelayContext(ctx context.Context, groupName string, opts DelayOptions) (map[string]int, error) {
+	opts = opts.withDefaults()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout+opts.Timeout)
+	defer cancel()
+
+	u := c.endpoint(groupPath, groupName, "delay") + "?" + opts.query().Encode()
+	resp, err := c.do(ctx, "GET", u, nil)
+	if StatusCode(err) == http.StatusNotFound {
+		return nil, &GroupNotFoundError{Group: groupName}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var result map[string]int
+	if err := decodeJSON(resp, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,132 @@
+package clash
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// UnauthorizedError means the controller rejected the secret (401).
+type UnauthorizedError struct {
+	Message string
+}
+
+func (e *UnauthorizedError) Error() string {
+	return "unauthorized: wrong or missing secret"
+}
+
+// GroupNotFoundError means the controller has no group with that name.
+type GroupNotFoundError struct {
+	Group string
+}
+
+func (e *GroupNotFoundError) Error() string {
+	return fmt.Sprintf("group %s not found", e.Group)
+}
+
+// ProxyNotFoundError means the controller has no proxy with that name.
+type ProxyNotFoundError struct {
+	Proxy string
+}
+
+func (e *ProxyNotFoundError) Error() string {
+	return fmt.Sprintf("proxy %s not found", e.Proxy)
+}
+
+// ProxyNotInGroupError means a selection named a proxy outside the group.
+type ProxyNotInGroupError struct {
+	Group string
+	Proxy string
+}
+
+func (e *ProxyNotInGroupError) Error() string {
+	return fmt.Sprintf("proxy %s not found in group %s", e.Proxy, e.Group)
+}
+
+// ProviderNotFoundError means the controller has no provider with that
+// name.
+type ProviderNotFoundError struct {
+	Provider string
+}
+
+func (e *ProviderNotFoundError) Error() string {
+	return fmt.Sprintf("provider %s not found", e.Provider)
+}
+
+// UnreachableError means no HTTP response was received: the connection was
+// refused, the socket is missing, TLS failed or the request timed out.
+type UnreachableError struct {
+	Address string
+	Err     error
+}
+
+func (e *UnreachableError) Error() string {
+	return fmt.Sprintf("controller %s unreachable: %v", e.Address, e.Err)
+}
+
+func (e *UnreachableError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeError means the controller answered with a body that could not be
+// decoded.
+type DecodeError struct {
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("failed to decode response: %v", e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// StatusError is any other unexpected status code.
+type StatusError struct {
+	StatusCode int
+	Message    string // "message" field of the error body, or the raw body
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code %d: %s", e.StatusCode, e.Message)
+}
+
+// StatusCode returns the HTTP status of a *StatusError in err's chain, or 0.
+func StatusCode(err error) int {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.StatusCode
+	}
+	return 0
+}
+
+// newStatusError reads the error body of a failed response. Mihomo sends
+// {"message": "..."}; anything else is kept verbatim.
+func newStatusError(resp *http.Response) error {
+	body, _ := io.ReadAll(resp.Body)
+	var payload struct {
+		Message string `json:"message"`
+	}
+	message := strings.TrimSpace(string(body))
+	if json.Unmarshal(body, &payload) == nil && payload.Message != "" {
+		message = payload.Message
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		return &UnauthorizedError{Message: message}
+	}
+	return &StatusError{StatusCode: resp.StatusCode, Message: message}
+}
+
+// transportError wraps a failed round trip. Cancellation by the caller is
+// passed through untouched so it can be told apart from a dead controller.
+func (c *Client) transportError(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return err
+	}
+	return &UnreachableError{Address: c.address, Err: err}
+}
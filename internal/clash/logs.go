@@ -0,0 +1,52 @@
+package clash
+
+import (
+	"context"
+	"net/url"
+)
+
+const logsPath = "logs"
+
+// Log levels accepted by /logs, from the most verbose.
+const (
+	LogDebug   = "debug"
+	LogInfo    = "info"
+	LogWarning = "warning"
+	LogError   = "error"
+)
+
+// LogLevelRank orders the /logs levels by severity, from 0 for debug to 3
+// for error. Other values rank -1.
+func LogLevelRank(level string) int {
+	switch level {
+	case LogDebug:
+		return 0
+	case LogInfo:
+		return 1
+	case LogWarning:
+		return 2
+	case LogError:
+		return 3
+	}
+	return -1
+}
+
+// LogEntry is one line of the /logs stream. Type is its level.
+type LogEntry struct {
+	Type    string `json:"type"`
+	Payload string `json:"payload"`
+}
+
+func (c *Client) StreamLogs(level string) (*Stream[LogEntry], error) {
+	return c.StreamLogsContext(context.Background(), level)
+}
+
+// StreamLogsContext streams core log lines at level or above; an empty
+// level uses the core's default (info).
+func (c *Client) StreamLogsContext(ctx context.Context, level string) (*Stream[LogEntry], error) {
+	query := url.Values{}
+	if level != "" {
+		query.Set("level", level)
+	}
+	return openStream[LogEntry](ctx, c, query, logsPath)
+}
@@ -0,0 +1,21 @@
+package clash
+
+import "context"
+
+const memoryPath = "memory"
+
+// MemoryUsage is one sample of Mihomo's /memory stream, sent every second.
+type MemoryUsage struct {
+	InUse   int64 `json:"inuse"`   // bytes
+	OSLimit int64 `json:"oslimit"` // bytes, 0 when unlimited
+}
+
+func (c *Client) StreamMemory() (*Stream[MemoryUsage], error) {
+	return c.StreamMemoryContext(context.Background())
+}
+
+// StreamMemoryContext streams the core's memory usage until ctx is done or
+// the stream is closed. Only Clash.Meta and Mihomo have /memory.
+func (c *Client) StreamMemoryContext(ctx context.Context) (*Stream[MemoryUsage], error) {
+	return openStream[MemoryUsage](ctx, c, nil, memoryPath)
+}
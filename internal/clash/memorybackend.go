@@ -0,0 +1,475 @@
+package clash
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+)
+
+// MemoryBackend is an in-memory Backend. It mimics Mihomo's behaviour for
+// selection and fixing closely enough for mock mode and tests.
+type MemoryBackend struct {
+	mu      sync.RWMutex
+	proxies map[string]Proxy
+	conns   []Connection
+	rules   []Rule
+	pps     map[string]ProxyProvider
+	rps     map[string]RuleProvider
+	config  Config
+
+	// simulate makes mock connections move traffic between calls
+	simulate bool
+	lastSim  time.Time
+
+	// streamInterval is the period of simulated streams
+	streamInterval time.Duration
+}
+
+// NewMemoryBackend returns a backend serving the given proxies. The map is
+// copied, later changes by the caller are not seen.
+func NewMemoryBackend(proxies map[string]Proxy) *MemoryBackend {
+	b := &MemoryBackend{proxies: make(map[string]Proxy, len(proxies)), config: MockConfig(), streamInterval: time.Second}
+	for name, p := range proxies {
+		b.proxies[name] = p
+	}
+	return b
+}
+
+// NewMockBackend returns a MemoryBackend with demo data.
+func NewMockBackend() *MemoryBackend {
+	b := NewMemoryBackend(MockProxies())
+	// Like a real core, offer every group in GLOBAL for global mode
+	b.proxies["GLOBAL"] = Proxy{
+		Name: "GLOBAL",
+		Type: "Selector",
+		Now:  "Proxy Group A",
+		All:  []string{"Proxy Group A", "Proxy Group B", "Proxy Group C"},
+	}
+	b.conns = mockConnections(b.proxies, time.Now())
+	b.rules = MockRules()
+	b.pps = MockProxyProviders(time.Now())
+	b.rps = MockRuleProviders(time.Now())
+	b.simulate = true
+	b.lastSim = time.Now()
+	return b
+}
+
+// mockConnections opens a few connections through the current selection of
+// every group.
+func mockConnections(proxies map[string]Proxy, now time.Time) []Connection {
+	hosts := []string{"www.google.com", "github.com", "api.openai.com", "www.youtube.com", "cdn.jsdelivr.net", "registry.npmjs.org"}
+	groups := []string{"Proxy Group A", "Proxy Group B", "Proxy Group C"}
+	var conns []Connection
+	for i, host := range hosts {
+		group := proxies[groups[i%len(groups)]]
+		conns = append(conns, Connection{
+			ID: fmt.Sprintf("mock-%04d", i+1),
+			Metadata: ConnectionMetadata{
+				Network:         "tcp",
+				Type:            "Mixed",
+				SourceIP:        "127.0.0.1",
+				SourcePort:      fmt.Sprint(50000 + i),
+				DestinationIP:   fmt.Sprintf("203.0.113.%d", 10+i),
+				DestinationPort: "443",
+				Host:            host,
+				Process:         "firefox",
+			},
+			Upload:      int64(MockDelay(host)) * 100,
+			Download:    int64(MockDelay(host)) * 4000,
+			Start:       now.Add(-time.Duration(i*97) * time.Second),
+			Chains:      []string{group.Now, group.Name},
+			Rule:        "DomainSuffix",
+			RulePayload: host,
+		})
+	}
+	return conns
+}
+
+// AddConnection tracks a connection, as if a client had opened it.
+func (b *MemoryBackend) AddConnection(conn Connection) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.conns = append(b.conns, conn)
+}
+
+// SetRules replaces the rule list.
+func (b *MemoryBackend) SetRules(rules []Rule) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rules = append([]Rule(nil), rules...)
+}
+
+// SetProxyProviders replaces the proxy providers.
+func (b *MemoryBackend) SetProxyProviders(providers map[string]ProxyProvider) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pps = make(map[string]ProxyProvider, len(providers))
+	for name, p := range providers {
+		b.pps[name] = p
+	}
+}
+
+// SetRuleProviders replaces the rule providers.
+func (b *MemoryBackend) SetRuleProviders(providers map[string]RuleProvider) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rps = make(map[string]RuleProvider, len(providers))
+	for name, p := range providers {
+		b.rps[name] = p
+	}
+}
+
+// MockProxies returns the demo data used in mock mode: three groups and
+// their member proxies.
+func MockProxies() map[string]Proxy {
+	groups := []Proxy{
+		{
+			Name: "Proxy Group A",
+			Type: "Selector",
+			Now:  "Proxy-1",
+			All:  []string{"Proxy-1", "Proxy-2", "Proxy-3", "Proxy-4", "Proxy-5", "Proxy-6", "Proxy-7"},
+		},
+		{
+			Name: "Proxy Group B",
+			Type: "URLTest",
+			Now:  "Auto-2",
+			All:  []string{"Auto-1", "Auto-2", "Auto-3", "Auto-4", "Auto-5", "Auto-6"},
+		},
+		{
+			Name: "Proxy Group C",
+			Type: "Selector",
+			Now:  "Direct-1",
+			All:  []string{"Direct-1", "Direct-2", "Direct-3", "Direct-4", "Direct-5", "Direct-6", "Direct-7", "Direct-8"},
+		},
+	}
+
+	proxies := make(map[string]Proxy)
+	for _, g := range groups {
+		proxies[g.Name] = g
+		for _, name := range g.All {
+			proxies[name] = Proxy{Name: name, Type: "Shadowsocks"}
+		}
+	}
+	return proxies
+}
+
+func (b *MemoryBackend) GetVersionContext(ctx context.Context) (*Version, error) {
+	return &Version{Version: "mock", Meta: true}, nil
+}
+
+func (b *MemoryBackend) GetProxiesContext(ctx context.Context) (*ProxiesResponse, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	// Hand out a copy so callers never race with later updates
+	proxies := make(map[string]Proxy, len(b.proxies))
+	for name, p := range b.proxies {
+		proxies[name] = p
+	}
+	return &ProxiesResponse{Proxies: proxies}, nil
+}
+
+func (b *MemoryBackend) SelectProxyContext(ctx context.Context, groupName, proxyName string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	group, ok := b.proxies[groupName]
+	if !ok {
+		return &GroupNotFoundError{Group: groupName}
+	}
+	for _, p := range group.All {
+		if p == proxyName {
+			group.Now = proxyName
+			// Selecting inside a URLTest group pins it, like Mihomo does
+			if group.Type == "URLTest" {
+				group.Fixed = proxyName
+			}
+			b.proxies[groupName] = group
+			return nil
+		}
+	}
+	return &ProxyNotInGroupError{Group: groupName, Proxy: proxyName}
+}
+
+func (b *MemoryBackend) ResetFixedProxyContext(ctx context.Context, groupName string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	group, ok := b.proxies[groupName]
+	if !ok {
+		return &GroupNotFoundError{Group: groupName}
+	}
+	group.Fixed = ""
+	b.proxies[groupName] = group
+	return nil
+}
+
+func (b *MemoryBackend) ProxyDelayContext(ctx context.Context, proxyName string, opts DelayOptions) (DelayResult, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if _, ok := b.proxies[proxyName]; !ok {
+		return DelayResult{}, &ProxyNotFoundError{Proxy: proxyName}
+	}
+	return DelayResult{Delay: MockDelay(proxyName)}, nil
+}
+
+func (b *MemoryBackend) GroupDelayContext(ctx context.Context, groupName string, opts DelayOptions) (map[string]int, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	group, ok := b.proxies[groupName]
+	if !ok {
+		return nil, &GroupNotFoundError{Group: groupName}
+	}
+	result := make(map[string]int, len(group.All))
+	for _, name := range group.All {
+		result[name] = MockDelay(name)
+	}
+	return result, nil
+}
+
+// MockDelay derives a stable, plausible delay from a proxy name. The mock
+// backend and the fake controller both report it, so demos and tests agree.
+func MockDelay(name string) int {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return 40 + int(h.Sum32()%360)
+}
+
+func (b *MemoryBackend) GetConnectionsContext(ctx context.Context) (*ConnectionsResponse, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.simulate {
+		elapsed := time.Since(b.lastSim).Seconds()
+		b.lastSim = time.Now()
+		for i := range b.conns {
+			rate := float64(MockDelay(b.conns[i].ID))
+			b.conns[i].Upload += int64(rate * 10 * elapsed)
+			b.conns[i].Download += int64(rate * 300 * elapsed)
+		}
+	}
+
+	resp := &ConnectionsResponse{Connections: append([]Connection(nil), b.conns...)}
+	for _, c := range b.conns {
+		resp.UploadTotal += c.Upload
+		resp.DownloadTotal += c.Download
+	}
+	return resp, nil
+}
+
+func (b *MemoryBackend) GetRulesContext(ctx context.Context) (*RulesResponse, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return &RulesResponse{Rules: append([]Rule(nil), b.rules...)}, nil
+}
+
+func (b *MemoryBackend) GetProxyProvidersContext(ctx context.Context) (*ProxyProvidersResponse, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	providers := make(map[string]ProxyProvider, len(b.pps))
+	for name, p := range b.pps {
+		p.Proxies = append([]Proxy(nil), p.Proxies...)
+		providers[name] = p
+	}
+	return &ProxyProvidersResponse{Providers: providers}, nil
+}
+
+func (b *MemoryBackend) UpdateProxyProviderContext(ctx context.Context, name string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	p, ok := b.pps[name]
+	if !ok {
+		return &ProviderNotFoundError{Provider: name}
+	}
+	p.UpdatedAt = time.Now()
+	b.pps[name] = p
+	return nil
+}
+
+// HealthCheckProxyProviderContext records a mock delay in the history of
+// every proxy of the provider.
+func (b *MemoryBackend) HealthCheckProxyProviderContext(ctx context.Context, name string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	p, ok := b.pps[name]
+	if !ok {
+		return &ProviderNotFoundError{Provider: name}
+	}
+	now := time.Now().Format(time.RFC3339)
+	proxies := make([]Proxy, len(p.Proxies))
+	for i, proxy := range p.Proxies {
+		proxy.History = []ProxyHistory{{Time: now, Delay: MockDelay(proxy.Name)}}
+		proxies[i] = proxy
+	}
+	p.Proxies = proxies
+	b.pps[name] = p
+	return nil
+}
+
+func (b *MemoryBackend) GetRuleProvidersContext(ctx context.Context) (*RuleProvidersResponse, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	providers := make(map[string]RuleProvider, len(b.rps))
+	for name, p := range b.rps {
+		providers[name] = p
+	}
+	return &RuleProvidersResponse{Providers: providers}, nil
+}
+
+func (b *MemoryBackend) UpdateRuleProviderContext(ctx context.Context, name string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	p, ok := b.rps[name]
+	if !ok {
+		return &ProviderNotFoundError{Provider: name}
+	}
+	p.UpdatedAt = time.Now()
+	b.rps[name] = p
+	return nil
+}
+
+func (b *MemoryBackend) GetConfigContext(ctx context.Context) (*Config, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	config := b.config
+	return &config, nil
+}
+
+// PatchConfigContext applies the patch, rejecting invalid values with the
+// 400 a real core answers.
+func (b *MemoryBackend) PatchConfigContext(ctx context.Context, patch ConfigPatch) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.config.Apply(patch); err != nil {
+		return &StatusError{StatusCode: 400, Message: "Body invalid"}
+	}
+	return nil
+}
+
+// ReloadConfigContext drops the runtime changes, as if the config file was
+// read again. Paths and payloads are not parsed.
+func (b *MemoryBackend) ReloadConfigContext(ctx context.Context, req ReloadConfigRequest) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.config = MockConfig()
+	return nil
+}
+
+// RestartContext behaves like a config reload; the mock never goes away.
+func (b *MemoryBackend) RestartContext(ctx context.Context) error {
+	return b.ReloadConfigContext(ctx, ReloadConfigRequest{})
+}
+
+// FlushFakeIPCacheContext succeeds; the mock has no fake-ip pool.
+func (b *MemoryBackend) FlushFakeIPCacheContext(ctx context.Context) error {
+	return nil
+}
+
+// FlushDNSCacheContext succeeds; the mock has no resolver.
+func (b *MemoryBackend) FlushDNSCacheContext(ctx context.Context) error {
+	return nil
+}
+
+func (b *MemoryBackend) CloseConnectionContext(ctx context.Context, id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, c := range b.conns {
+		if c.ID == id {
+			b.conns = append(b.conns[:i], b.conns[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBackend) CloseAllConnectionsContext(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.conns = nil
+	return nil
+}
+
+// StreamTrafficContext reports the rates of the simulated connections, or
+// zero when not simulating.
+func (b *MemoryBackend) StreamTrafficContext(ctx context.Context) (*Stream[Traffic], error) {
+	return tickerStream(ctx, b.streamInterval, func() Traffic {
+		b.mu.RLock()
+		defer b.mu.RUnlock()
+
+		var t Traffic
+		if !b.simulate {
+			return t
+		}
+		// Vary the load over time so the history is worth looking at
+		load := 0.6 + 0.4*math.Sin(float64(time.Now().Unix())/5)
+		for _, c := range b.conns {
+			rate := float64(MockDelay(c.ID))
+			t.Up += int64(rate * 10 * load)
+			t.Down += int64(rate * 300 * load)
+		}
+		return t
+	}), nil
+}
+
+// StreamMemoryContext reports a slowly leaking core when simulating, or
+// zero otherwise.
+func (b *MemoryBackend) StreamMemoryContext(ctx context.Context) (*Stream[MemoryUsage], error) {
+	start := time.Now()
+	return tickerStream(ctx, b.streamInterval, func() MemoryUsage {
+		b.mu.RLock()
+		defer b.mu.RUnlock()
+
+		if !b.simulate {
+			return MemoryUsage{}
+		}
+		elapsed := time.Since(start).Seconds()
+		const base, leak, swing = 48 << 20, 64 << 10, 4 << 20 // bytes, bytes/s, bytes
+		inuse := base + int64(leak*elapsed) + int64(swing*math.Sin(elapsed/3))
+		return MemoryUsage{InUse: inuse}
+	}), nil
+}
+
+// mockLogs are replayed in a loop by StreamLogsContext.
+var mockLogs = []LogEntry{
+	{LogInfo, "[TCP] 127.0.0.1:50312 --> www.google.com:443 match DomainSuffix(google.com) using Proxy Group A[Proxy-1]"},
+	{LogDebug, "[DNS] www.google.com --> [142.250.72.36]"},
+	{LogInfo, "[TCP] 127.0.0.1:50318 --> github.com:443 match DomainSuffix(github.com) using Proxy Group B[Auto-2]"},
+	{LogDebug, "[Sniffer] Sniff TCP [127.0.0.1:50318-->140.82.112.4:443] success, replace domain [140.82.112.4]-->[github.com]"},
+	{LogWarning, "[TCP] dial Proxy Group B (match DomainSuffix/openai.com) 127.0.0.1:50320 --> api.openai.com:443 error: i/o timeout"},
+	{LogInfo, "[UDP] 127.0.0.1:53012 --> 1.1.1.1:53 match Match using Proxy Group C[Direct-1]"},
+	{LogError, "[Provider] subscription pull error: Get \"https://example.com/sub\": context deadline exceeded"},
+}
+
+// StreamLogsContext replays canned log lines at level or above.
+func (b *MemoryBackend) StreamLogsContext(ctx context.Context, level string) (*Stream[LogEntry], error) {
+	if level == "" {
+		level = LogInfo
+	}
+	min := LogLevelRank(level)
+	if min < 0 {
+		return nil, &StatusError{StatusCode: 400, Message: "Body invalid"}
+	}
+	next := 0
+	return tickerStream(ctx, b.streamInterval/2, func() LogEntry {
+		for {
+			e := mockLogs[next%len(mockLogs)]
+			next++
+			if LogLevelRank(e.Type) >= min {
+				return e
+			}
+		}
+	}), nil
+}
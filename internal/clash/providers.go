@@ -0,0 +1,229 @@
+package clash
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+const (
+	providersPath      = "providers"
+	proxyProvidersPath = "proxies"
+	ruleProvidersPath  = "rules"
+)
+
+// ProxyProvider is a proxy provider, usually a subscription.
+type ProxyProvider struct {
+	Name        string    `json:"name"`
+	Type        string    `json:"type"`        // always "Proxy"
+	VehicleType string    `json:"vehicleType"` // HTTP, File, Inline, or Compatible for the built-in one
+	Proxies     []Proxy   `json:"proxies"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+	// SubscriptionInfo is parsed from the subscription-userinfo header of
+	// HTTP providers, nil when the server did not send one.
+	SubscriptionInfo *SubscriptionInfo `json:"subscriptionInfo,omitempty"`
+}
+
+// SubscriptionInfo is the traffic quota of a subscription, in bytes.
+type SubscriptionInfo struct {
+	Upload   int64 `json:"Upload"`
+	Download int64 `json:"Download"`
+	Total    int64 `json:"Total"`
+	Expire   int64 `json:"Expire"` // unix seconds, 0 when it does not expire
+}
+
+type ProxyProvidersResponse struct {
+	Providers map[string]ProxyProvider `json:"providers"`
+}
+
+func (c *Client) GetProxyProviders() (*ProxyProvidersResponse, error) {
+	return c.GetProxyProvidersContext(context.Background())
+}
+
+func (c *Client) GetProxyProvidersContext(ctx context.Context) (*ProxyProvidersResponse, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.do(ctx, "GET", c.endpoint(providersPath, proxyProvidersPath), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result ProxyProvidersResponse
+	if err := decodeJSON(resp, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// UpdateProxyProvider makes the core fetch a provider again.
+func (c *Client) UpdateProxyProvider(name string) error {
+	return c.UpdateProxyProviderContext(context.Background(), name)
+}
+
+func (c *Client) UpdateProxyProviderContext(ctx context.Context, name string) error {
+	// The core answers once the download finished
+	ctx, cancel := context.WithTimeout(ctx, c.timeout+DefaultDelayTimeout)
+	defer cancel()
+
+	resp, err := c.do(ctx, "PUT", c.endpoint(providersPath, proxyProvidersPath, name), nil)
+	if StatusCode(err) == http.StatusNotFound {
+		return &ProviderNotFoundError{Provider: name}
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// HealthCheckProxyProvider tests every proxy of a provider. The results
+// show up in the proxies' History.
+func (c *Client) HealthCheckProxyProvider(name string) error {
+	return c.HealthCheckProxyProviderContext(context.Background(), name)
+}
+
+func (c *Client) HealthCheckProxyProviderContext(ctx context.Context, name string) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout+DefaultDelayTimeout)
+	defer cancel()
+
+	resp, err := c.do(ctx, "GET", c.endpoint(providersPath, proxyProvidersPath, name, "healthcheck"), nil)
+	if StatusCode(err) == http.StatusNotFound {
+		return &ProviderNotFoundError{Provider: name}
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// RuleProvider is a rule-set provider (Clash Premium and Mihomo).
+type RuleProvider struct {
+	Name        string    `json:"name"`
+	Type        string    `json:"type"`     // always "Rule"
+	Behavior    string    `json:"behavior"` // Domain, IPCIDR or Classical
+	Format      string    `json:"format"`   // YamlRule, TextRule or MrsRule; empty before Mihomo
+	RuleCount   int       `json:"ruleCount"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+	VehicleType string    `json:"vehicleType"` // HTTP, File or Inline
+}
+
+type RuleProvidersResponse struct {
+	Providers map[string]RuleProvider `json:"providers"`
+}
+
+func (c *Client) GetRuleProviders() (*RuleProvidersResponse, error) {
+	return c.GetRuleProvidersContext(context.Background())
+}
+
+func (c *Client) GetRuleProvidersContext(ctx context.Context) (*RuleProvidersResponse, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.do(ctx, "GET", c.endpoint(providersPath, ruleProvidersPath), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result RuleProvidersResponse
+	if err := decodeJSON(resp, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// UpdateRuleProvider makes the core fetch a rule set again.
+func (c *Client) UpdateRuleProvider(name string) error {
+	return c.UpdateRuleProviderContext(context.Background(), name)
+}
+
+func (c *Client) UpdateRuleProviderContext(ctx context.Context, name string) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout+DefaultDelayTimeout)
+	defer cancel()
+
+	resp, err := c.do(ctx, "PUT", c.endpoint(providersPath, ruleProvidersPath, name), nil)
+	if StatusCode(err) == http.StatusNotFound {
+		return &ProviderNotFoundError{Provider: name}
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// MockProxyProviders returns the demo providers used in mock mode: a
+// subscription holding the members of "Proxy Group A" and a local file with
+// those of "Proxy Group C".
+func MockProxyProviders(now time.Time) map[string]ProxyProvider {
+	proxies := MockProxies()
+	members := func(group string) []Proxy {
+		var out []Proxy
+		for _, name := range proxies[group].All {
+			out = append(out, proxies[name])
+		}
+		return out
+	}
+	return map[string]ProxyProvider{
+		"Subscription": {
+			Name:        "Subscription",
+			Type:        "Proxy",
+			VehicleType: "HTTP",
+			Proxies:     members("Proxy Group A"),
+			UpdatedAt:   now.Add(-3 * time.Hour),
+			SubscriptionInfo: &SubscriptionInfo{
+				Upload:   3 << 30,
+				Download: 41 << 30,
+				Total:    200 << 30,
+				Expire:   now.AddDate(0, 2, 0).Unix(),
+			},
+		},
+		"Local": {
+			Name:        "Local",
+			Type:        "Proxy",
+			VehicleType: "File",
+			Proxies:     members("Proxy Group C"),
+			UpdatedAt:   now.Add(-26 * time.Hour),
+		},
+	}
+}
+
+// MockRuleProviders returns the demo rule providers used in mock mode,
+// including the "streaming" set referenced by MockRules.
+func MockRuleProviders(now time.Time) map[string]RuleProvider {
+	return map[string]RuleProvider{
+		"streaming": {
+			Name:        "streaming",
+			Type:        "Rule",
+			Behavior:    "Domain",
+			Format:      "MrsRule",
+			RuleCount:   1532,
+			UpdatedAt:   now.Add(-5 * time.Hour),
+			VehicleType: "HTTP",
+		},
+		"ads": {
+			Name:        "ads",
+			Type:        "Rule",
+			Behavior:    "Domain",
+			Format:      "TextRule",
+			RuleCount:   48210,
+			UpdatedAt:   now.Add(-9 * 24 * time.Hour),
+			VehicleType: "HTTP",
+		},
+		"lan": {
+			Name:        "lan",
+			Type:        "Rule",
+			Behavior:    "IPCIDR",
+			Format:      "YamlRule",
+			RuleCount:   3,
+			UpdatedAt:   now.Add(-40 * time.Minute),
+			VehicleType: "File",
+		},
+	}
+}
@@ -0,0 +1,76 @@
+package clash
+
+import (
+	"context"
+	"time"
+)
+
+const rulesPath = "rules"
+
+// Rule is an entry of the core's rule list, in matching order.
+type Rule struct {
+	Index   int    `json:"index"` // Mihomo only
+	Type    string `json:"type"`  // e.g. DomainSuffix, IPCIDR, RuleSet, Match
+	Payload string `json:"payload"`
+	Proxy   string `json:"proxy"` // target group or proxy
+	// Size is the number of rules in a RuleSet, -1 for other types. Mihomo
+	// only.
+	Size  int        `json:"size"`
+	Extra *RuleExtra `json:"extra,omitempty"`
+}
+
+// RuleExtra holds the per-rule statistics of recent Mihomo versions.
+type RuleExtra struct {
+	Disabled  bool      `json:"disabled"`
+	HitCount  uint64    `json:"hitCount"`
+	HitAt     time.Time `json:"hitAt"`
+	MissCount uint64    `json:"missCount"`
+	MissAt    time.Time `json:"missAt"`
+}
+
+type RulesResponse struct {
+	Rules []Rule `json:"rules"`
+}
+
+func (c *Client) GetRules() (*RulesResponse, error) {
+	return c.GetRulesContext(context.Background())
+}
+
+func (c *Client) GetRulesContext(ctx context.Context) (*RulesResponse, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.do(ctx, "GET", c.endpoint(rulesPath), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result RulesResponse
+	if err := decodeJSON(resp, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// MockRules returns the demo rules used in mock mode, targeting the groups
+// of MockProxies.
+func MockRules() []Rule {
+	rules := []Rule{
+		{Type: "DomainSuffix", Payload: "google.com", Proxy: "Proxy Group A"},
+		{Type: "DomainSuffix", Payload: "github.com", Proxy: "Proxy Group B"},
+		{Type: "DomainKeyword", Payload: "openai", Proxy: "Proxy Group B"},
+		{Type: "RuleSet", Payload: "streaming", Proxy: "Proxy Group A", Size: 1532},
+		{Type: "DomainSuffix", Payload: "local", Proxy: "DIRECT"},
+		{Type: "IPCIDR", Payload: "192.168.0.0/16", Proxy: "DIRECT"},
+		{Type: "GeoIP", Payload: "CN", Proxy: "Proxy Group C"},
+		{Type: "Match", Payload: "", Proxy: "Proxy Group A"},
+	}
+	for i := range rules {
+		rules[i].Index = i
+		if rules[i].Type != "RuleSet" {
+			rules[i].Size = -1
+		}
+	}
+	return rules
+}
@@ -0,0 +1,179 @@
+package clash
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/wallacegibbon/proxy-controller-tui/internal/websocket"
+)
+
+// Stream delivers the values of a streaming endpoint such as /traffic, one
+// per Next call, until it is closed or its context is done.
+type Stream[T any] struct {
+	ctx   context.Context
+	next  func() (T, error)
+	close func() error
+
+	once     sync.Once
+	closeErr error
+}
+
+// Next blocks until the next value arrives. It returns io.EOF when the
+// controller ends the stream and the context's error once it is done.
+func (s *Stream[T]) Next() (T, error) {
+	v, err := s.next()
+	if err != nil && s.ctx.Err() != nil {
+		err = s.ctx.Err()
+	}
+	return v, err
+}
+
+// Close stops the stream; a blocked Next returns.
+func (s *Stream[T]) Close() error {
+	s.once.Do(func() { s.closeErr = s.close() })
+	return s.closeErr
+}
+
+// openStream starts a streaming GET, as chunked JSON lines or, when the
+// client is configured for it, as WebSocket text messages. Unlike other
+// requests it has no timeout: it lives until ctx is done or it is closed.
+func openStream[T any](ctx context.Context, c *Client, query url.Values, segments ...string) (*Stream[T], error) {
+	ctx, cancel := context.WithCancel(ctx)
+	if query == nil {
+		query = url.Values{}
+	}
+
+	var (
+		read func() ([]byte, error)
+		body io.Closer
+	)
+	if c.webSocket {
+		conn, err := c.dialWebSocket(ctx, c.endpoint(segments...), query)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		read, body = conn.ReadMessage, conn
+	} else {
+		u := c.endpoint(segments...)
+		if len(query) > 0 {
+			u += "?" + query.Encode()
+		}
+		resp, err := c.do(ctx, "GET", u, nil)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		dec := json.NewDecoder(resp.Body)
+		read = func() ([]byte, error) {
+			var raw json.RawMessage
+			err := dec.Decode(&raw)
+			return raw, err
+		}
+		body = resp.Body
+	}
+
+	stop := context.AfterFunc(ctx, func() { body.Close() })
+	return &Stream[T]{
+		ctx: ctx,
+		next: func() (T, error) {
+			var v T
+			b, err := read()
+			if err != nil {
+				var syntax *json.SyntaxError
+				if errors.As(err, &syntax) {
+					return v, &DecodeError{Err: err}
+				}
+				return v, err
+			}
+			if err := json.Unmarshal(b, &v); err != nil {
+				return v, &DecodeError{Err: err}
+			}
+			return v, nil
+		},
+		close: func() error {
+			stop()
+			cancel()
+			return body.Close()
+		},
+	}, nil
+}
+
+// dialWebSocket upgrades a GET to a WebSocket. Browsers cannot set headers
+// on WebSocket requests, so the controller takes the secret as ?token=.
+func (c *Client) dialWebSocket(ctx context.Context, u string, query url.Values) (*websocket.Conn, error) {
+	if c.secret != "" {
+		query.Set("token", c.secret)
+	}
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	key, err := websocket.NewKey()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, c.transportError(ctx, err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		defer resp.Body.Close()
+		if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+			return nil, &StatusError{StatusCode: resp.StatusCode, Message: "controller did not upgrade to WebSocket"}
+		}
+		return nil, newStatusError(resp)
+	}
+	rwc, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok || resp.Header.Get("Sec-WebSocket-Accept") != websocket.AcceptKey(key) {
+		resp.Body.Close()
+		return nil, &DecodeError{Err: errors.New("invalid WebSocket handshake")}
+	}
+	return websocket.NewConn(rwc, rwc, true), nil
+}
+
+// tickerStream produces a value from sample every interval, for in-memory
+// backends.
+func tickerStream[T any](ctx context.Context, interval time.Duration, sample func() T) *Stream[T] {
+	ctx, cancel := context.WithCancel(ctx)
+	first := true
+	return &Stream[T]{
+		ctx: ctx,
+		next: func() (T, error) {
+			var zero T
+			if first {
+				first = false
+			} else {
+				select {
+				case <-time.After(interval):
+				case <-ctx.Done():
+					return zero, ctx.Err()
+				}
+			}
+			if ctx.Err() != nil {
+				return zero, ctx.Err()
+			}
+			return sample(), nil
+		},
+		close: func() error {
+			cancel()
+			return nil
+		},
+	}
+}
@@ -0,0 +1,221 @@
+package clash
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCert is a certificate with its key, signed by parent or self-signed.
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+func newTestCert(t *testing.T, template *x509.Certificate, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+func newTestCA(t *testing.T) *testCert {
+	return newTestCert(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "test CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key}
+}
+
+// writePEM writes the certificate and key to dir and returns their paths.
+func (c *testCert) writePEM(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+// newTestTLSServer serves an empty proxy list with a certificate for
+// 127.0.0.1 signed by ca. configure may require client certificates.
+func newTestTLSServer(t *testing.T, ca *testCert, configure func(*tls.Config)) *httptest.Server {
+	t.Helper()
+	server := newTestCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "controller"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+	}, ca)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(ProxiesResponse{Proxies: map[string]Proxy{}})
+	}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{server.tlsCertificate()}}
+	if configure != nil {
+		configure(srv.TLS)
+	}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestTLSFingerprintPin(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(ProxiesResponse{Proxies: map[string]Proxy{}})
+	}))
+	defer srv.Close()
+
+	sum := sha256.Sum256(srv.Certificate().Raw)
+
+	c, err := NewClient(Options{BaseURL: srv.URL, TLS: TLSOptions{Fingerprint: hex.EncodeToString(sum[:])}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetProxies(); err != nil {
+		t.Errorf("Expected pinned certificate to be accepted, got %v", err)
+	}
+
+	sum[0] ^= 0xff
+	c, err = NewClient(Options{BaseURL: srv.URL, TLS: TLSOptions{Fingerprint: hex.EncodeToString(sum[:])}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetProxies(); err == nil {
+		t.Errorf("Expected mismatched fingerprint to be rejected")
+	}
+
+	// Without any TLS option the self-signed certificate is rejected
+	c, err = NewClient(Options{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetProxies(); err == nil {
+		t.Errorf("Expected self-signed certificate to be rejected without a pin")
+	}
+}
+
+func TestTLSCustomCA(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	srv := newTestTLSServer(t, ca, nil)
+	caFile, _ := ca.writePEM(t, dir, "ca")
+
+	c, err := NewClient(Options{BaseURL: srv.URL, TLS: TLSOptions{CAFile: caFile}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetProxies(); err != nil {
+		t.Errorf("Expected the CA file to be trusted, got %v", err)
+	}
+
+	// A different CA does not vouch for the server
+	otherFile, _ := newTestCA(t).writePEM(t, dir, "other")
+	c, err = NewClient(Options{BaseURL: srv.URL, TLS: TLSOptions{CAFile: otherFile}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetProxies(); err == nil {
+		t.Errorf("Expected a server signed by another CA to be rejected")
+	}
+
+	invalid := filepath.Join(dir, "invalid.pem")
+	if err := os.WriteFile(invalid, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for name, file := range map[string]string{"missing": filepath.Join(dir, "missing.pem"), "invalid": invalid} {
+		if _, err := NewClient(Options{BaseURL: srv.URL, TLS: TLSOptions{CAFile: file}}); err == nil {
+			t.Errorf("Expected the %s CA file to be rejected", name)
+		}
+	}
+}
+
+func TestTLSClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	srv := newTestTLSServer(t, ca, func(cfg *tls.Config) {
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		cfg.ClientCAs = pool
+	})
+	caFile, _ := ca.writePEM(t, dir, "ca")
+	client := newTestCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "tui"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+	}, ca)
+	certFile, keyFile := client.writePEM(t, dir, "client")
+
+	c, err := NewClient(Options{BaseURL: srv.URL, TLS: TLSOptions{CAFile: caFile, ClientCert: certFile, ClientKey: keyFile}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetProxies(); err != nil {
+		t.Errorf("Expected the client certificate to be accepted, got %v", err)
+	}
+
+	c, err = NewClient(Options{BaseURL: srv.URL, TLS: TLSOptions{CAFile: caFile}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetProxies(); err == nil {
+		t.Errorf("Expected the server to refuse a client without certificate")
+	}
+
+	// Half a pair is a configuration error
+	for name, opts := range map[string]TLSOptions{
+		"cert without key": {ClientCert: certFile},
+		"key without cert": {ClientKey: keyFile},
+		"mismatched pair":  {ClientCert: certFile, ClientKey: filepath.Join(dir, "ca.key")},
+	} {
+		if _, err := NewClient(Options{BaseURL: srv.URL, TLS: opts}); err == nil {
+			t.Errorf("Expected %s to be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package clash
+
+import "context"
+
+const trafficPath = "traffic"
+
+// Traffic is one sample of the /traffic stream, sent every second. Up and
+// Down are bytes per second; the totals are only sent by newer Mihomo.
+type Traffic struct {
+	Up        int64 `json:"up"`
+	Down      int64 `json:"down"`
+	UpTotal   int64 `json:"upTotal,omitempty"`
+	DownTotal int64 `json:"downTotal,omitempty"`
+}
+
+func (c *Client) StreamTraffic() (*Stream[Traffic], error) {
+	return c.StreamTrafficContext(context.Background())
+}
+
+// StreamTrafficContext streams the core's throughput until ctx is done or
+// the stream is closed.
+func (c *Client) StreamTrafficContext(ctx context.Context) (*Stream[Traffic], error) {
+	return openStream[Traffic](ctx, c, nil, trafficPath)
+}
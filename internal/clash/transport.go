@@ -0,0 +1,34 @@
+package clash
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const unixScheme = "unix://"
+
+// unixHostURL replaces the unix socket path in request URLs. The host is
+// never resolved, it only has to make the URL valid for net/http.
+const unixHostURL = "http://unix"
+
+// splitUnixAddress reports whether baseURL points to a unix socket
+// (unix:///path/to/mihomo.sock) and returns the socket path.
+func splitUnixAddress(baseURL string) (string, bool) {
+	if !strings.HasPrefix(baseURL, unixScheme) {
+		return "", false
+	}
+	return strings.TrimPrefix(baseURL, unixScheme), true
+}
+
+// newUnixTransport returns a transport that sends every request over the
+// given unix socket regardless of the request URL.
+func newUnixTransport(socketPath string) *http.Transport {
+	var d net.Dialer
+	return &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return d.DialContext(ctx, "unix", socketPath)
+		},
+	}
+}
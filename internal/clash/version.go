@@ -0,0 +1,113 @@
+package clash
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+const versionPath = "version"
+
+// Version is the answer of GET /version.
+type Version struct {
+	Version string `json:"version"`
+	Meta    bool   `json:"meta"`    // Clash.Meta and Mihomo
+	Premium bool   `json:"premium"` // Clash Premium
+}
+
+// Capabilities lists optional controller features. Cores that lack one
+// answer the endpoint with 404 or a generic 400.
+type Capabilities struct {
+	GroupDelay          bool // GET /group/{name}/delay
+	UnfixProxy          bool // DELETE /proxies/{name}
+	ProviderHealthcheck bool // GET /providers/proxies/{name}/healthcheck
+	UnixSocket          bool // external-controller-unix
+	RuleProviders       bool // /providers/rules
+	Restart             bool // POST /restart
+	FlushCache          bool // POST /cache/{fakeip,dns}/flush
+}
+
+// AllCapabilities is assumed while the core's version is unknown.
+var AllCapabilities = Capabilities{
+	GroupDelay:          true,
+	UnfixProxy:          true,
+	ProviderHealthcheck: true,
+	UnixSocket:          true,
+	RuleProviders:       true,
+	Restart:             true,
+	FlushCache:          true,
+}
+
+// Core names the controller implementation.
+func (v Version) Core() string {
+	switch {
+	case v.Meta && strings.Contains(strings.ToLower(v.Version), "meta"):
+		return "Clash.Meta"
+	case v.Meta:
+		return "Mihomo"
+	case v.Premium:
+		return "Clash Premium"
+	}
+	return "Clash"
+}
+
+func (v Version) String() string {
+	return v.Core() + " " + v.Version
+}
+
+// Capabilities derives the supported features from the core type and
+// version.
+func (v Version) Capabilities() Capabilities {
+	if v.Meta {
+		caps := AllCapabilities
+		// external-controller-unix arrived in Mihomo v1.18.0
+		caps.UnixSocket = v.atLeast(1, 18)
+		return caps
+	}
+	return Capabilities{
+		// Proxy providers and their health check arrived in Clash v0.18.0;
+		// Premium builds are versioned by date
+		ProviderHealthcheck: v.Premium || v.atLeast(0, 18),
+		RuleProviders:       v.Premium,
+	}
+}
+
+// atLeast reports whether the version number is major.minor or later.
+// Builds without a number, like Mihomo's "alpha-0c0a8a0", are taken to be
+// recent.
+func (v Version) atLeast(major, minor int) bool {
+	for _, field := range strings.FieldsFunc(v.Version, func(r rune) bool { return r == ' ' || r == '-' }) {
+		parts := strings.Split(strings.TrimPrefix(field, "v"), ".")
+		if len(parts) < 2 {
+			continue
+		}
+		gotMajor, err1 := strconv.Atoi(parts[0])
+		gotMinor, err2 := strconv.Atoi(parts[1])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		return gotMajor > major || (gotMajor == major && gotMinor >= minor)
+	}
+	return true
+}
+
+func (c *Client) GetVersion() (*Version, error) {
+	return c.GetVersionContext(context.Background())
+}
+
+func (c *Client) GetVersionContext(ctx context.Context) (*Version, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.do(ctx, "GET", c.endpoint(versionPath), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result Version
+	if err := decodeJSON(resp, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
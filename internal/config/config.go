@@ -0,0 +1,250 @@
+// Package config resolves the runtime settings of the TUI from command-line
+// flags, environment variables and an optional config file.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const appName = "proxy-controller-tui"
+
+// Environment variables recognised by Load.
+const (
+	EnvController = "CLASH_CONTROLLER"
+	EnvSecret     = "MIHOMO_SECRET"
+	EnvMock       = "MOCK_CLASH"
+)
+
+// Config holds everything needed to reach the Clash/Mihomo controller.
+//
+// Values are resolved with the precedence (highest first):
+// flags > environment > config file > defaults.
+type Config struct {
+	Controller string `json:"controller"`
+	Secret     string `json:"secret"`
+	Mock       bool   `json:"mock"`
+
+	// Timeout bounds each controller request, e.g. "10s". Zero uses the
+	// client default.
+	Timeout Duration `json:"timeout"`
+
+	// WebSocket streams /traffic and friends over WebSocket instead of
+	// chunked HTTP.
+	WebSocket bool `json:"websocket"`
+
+	// TLS settings for https:// controllers
+	CAFile             string `json:"ca-file"`
+	Fingerprint        string `json:"fingerprint"`
+	ClientCert         string `json:"client-cert"`
+	ClientKey          string `json:"client-key"`
+	InsecureSkipVerify bool   `json:"insecure-skip-verify"`
+
+	// CloseConnectionsOnSwitch closes the connections going through a group
+	// after another proxy is selected in it, so they reconnect on the new one.
+	CloseConnectionsOnSwitch bool `json:"close-connections-on-switch"`
+
+	// MemoryThreshold turns the core memory indicator red above it, e.g.
+	// "512MB". Zero disables the warning.
+	MemoryThreshold ByteSize `json:"memory-threshold"`
+}
+
+// Duration is a time.Duration written as a string ("5s", "1m") in the
+// config file.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"10s\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// ByteSize is a size written as "512MB", "1.5GiB" or a plain number of
+// bytes. Units are binary (1KB = 1024 bytes), like the sizes the TUI shows.
+type ByteSize int64
+
+var byteUnits = map[string]float64{
+	"": 1, "B": 1,
+	"K": 1 << 10, "KB": 1 << 10, "KIB": 1 << 10,
+	"M": 1 << 20, "MB": 1 << 20, "MIB": 1 << 20,
+	"G": 1 << 30, "GB": 1 << 30, "GIB": 1 << 30,
+}
+
+// ParseByteSize parses a size such as "512MB".
+func ParseByteSize(s string) (ByteSize, error) {
+	t := strings.TrimSpace(s)
+	i := strings.IndexFunc(t, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i < 0 {
+		i = len(t)
+	}
+	n, err := strconv.ParseFloat(t[:i], 64)
+	unit, ok := byteUnits[strings.ToUpper(strings.TrimSpace(t[i:]))]
+	if err != nil || !ok || n < 0 {
+		return 0, fmt.Errorf("invalid size %q, use e.g. \"512MB\"", s)
+	}
+	return ByteSize(n * unit), nil
+}
+
+func (b *ByteSize) UnmarshalJSON(data []byte) error {
+	var n int64
+	if err := json.Unmarshal(data, &n); err == nil {
+		*b = ByteSize(n)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("size must be a number or a string like \"512MB\": %w", err)
+	}
+	return b.Set(s)
+}
+
+// Set implements flag.Value.
+func (b *ByteSize) Set(s string) error {
+	v, err := ParseByteSize(s)
+	if err != nil {
+		return err
+	}
+	*b = v
+	return nil
+}
+
+func (b *ByteSize) String() string {
+	return strconv.FormatInt(int64(*b), 10)
+}
+
+// DefaultPath returns the config file location under $XDG_CONFIG_HOME,
+// falling back to ~/.config when the variable is unset.
+func DefaultPath(getenv func(string) string) (string, error) {
+	dir := getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to locate config directory: %w", err)
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, appName, "config.json"), nil
+}
+
+// Load parses args (without the program name) and merges them with the
+// environment and config file. getenv is usually os.Getenv.
+func Load(args []string, getenv func(string) string) (Config, error) {
+	fs := flag.NewFlagSet(appName, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	configPath := fs.String("config", "", "path to the config file")
+	controller := fs.String("controller", "", "controller address, e.g. http://127.0.0.1:9090")
+	secret := fs.String("secret", "", "controller API secret")
+	mock := fs.Bool("mock", false, "use built-in mock data instead of a controller")
+	caFile := fs.String("ca-file", "", "PEM CA bundle for https controllers")
+	fingerprint := fs.String("fingerprint", "", "SHA-256 fingerprint of the controller certificate")
+	clientCert := fs.String("client-cert", "", "PEM client certificate for mTLS")
+	clientKey := fs.String("client-key", "", "PEM client key for mTLS")
+	insecure := fs.Bool("insecure-skip-verify", false, "do not verify the controller certificate")
+	timeout := fs.Duration("timeout", 0, "per-request timeout (default 10s)")
+	webSocket := fs.Bool("websocket", false, "use WebSocket for streaming endpoints")
+	var memoryThreshold ByteSize
+	fs.Var(&memoryThreshold, "memory-threshold", "warn when the core uses more memory, e.g. 512MB")
+	closeOnSwitch := fs.Bool("close-connections-on-switch", false, "close a group's connections after switching its proxy")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.SetOutput(os.Stderr)
+			fs.Usage()
+		}
+		return Config{}, err
+	}
+
+	var cfg Config
+
+	// Config file: an explicit --config must exist, the default one may not.
+	path := *configPath
+	explicit := path != ""
+	if !explicit {
+		p, err := DefaultPath(getenv)
+		if err != nil {
+			return Config{}, err
+		}
+		path = p
+	}
+	if err := readFile(path, &cfg); err != nil {
+		if explicit || !errors.Is(err, os.ErrNotExist) {
+			return Config{}, err
+		}
+	}
+
+	// Environment
+	if v := getenv(EnvController); v != "" {
+		cfg.Controller = v
+	}
+	if v := getenv(EnvSecret); v != "" {
+		cfg.Secret = v
+	}
+	if v := getenv(EnvMock); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid %s value %q: %w", EnvMock, v, err)
+		}
+		cfg.Mock = b
+	}
+
+	// Flags, only those given explicitly
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "controller":
+			cfg.Controller = *controller
+		case "secret":
+			cfg.Secret = *secret
+		case "mock":
+			cfg.Mock = *mock
+		case "ca-file":
+			cfg.CAFile = *caFile
+		case "fingerprint":
+			cfg.Fingerprint = *fingerprint
+		case "client-cert":
+			cfg.ClientCert = *clientCert
+		case "client-key":
+			cfg.ClientKey = *clientKey
+		case "insecure-skip-verify":
+			cfg.InsecureSkipVerify = *insecure
+		case "timeout":
+			cfg.Timeout = Duration(*timeout)
+		case "websocket":
+			cfg.WebSocket = *webSocket
+		case "close-connections-on-switch":
+			cfg.CloseConnectionsOnSwitch = *closeOnSwitch
+		case "memory-threshold":
+			cfg.MemoryThreshold = memoryThreshold
+		}
+	})
+
+	if (cfg.ClientCert == "") != (cfg.ClientKey == "") {
+		return Config{}, errors.New("client-cert and client-key must be set together")
+	}
+
+	return cfg, nil
+}
+
+func readFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
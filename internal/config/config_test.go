@@ -0,0 +1,130 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func envFunc(env map[string]string) func(string) string {
+	return func(key string) string { return env[key] }
+}
+
+func writeConfig(t *testing.T, dir, content string) {
+	t.Helper()
+	path := filepath.Join(dir, appName, "config.json")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, `{"controller": "http://file:9090", "secret": "file-secret", "mock": true, "close-connections-on-switch": true}`)
+
+	// Config file only
+	cfg, err := Load(nil, envFunc(map[string]string{"XDG_CONFIG_HOME": dir}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Controller != "http://file:9090" || cfg.Secret != "file-secret" || !cfg.Mock || !cfg.CloseConnectionsOnSwitch {
+		t.Errorf("Expected values from config file, got %+v", cfg)
+	}
+
+	// Environment overrides the config file
+	env := map[string]string{
+		"XDG_CONFIG_HOME": dir,
+		EnvController:     "http://env:9090",
+		EnvMock:           "0",
+	}
+	cfg, err = Load(nil, envFunc(env))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Controller != "http://env:9090" || cfg.Secret != "file-secret" || cfg.Mock {
+		t.Errorf("Expected environment to override config file, got %+v", cfg)
+	}
+
+	// Flags override the environment
+	cfg, err = Load([]string{"--controller", "http://flag:9090", "--secret", "flag-secret", "--close-connections-on-switch=false"}, envFunc(env))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Controller != "http://flag:9090" || cfg.Secret != "flag-secret" || cfg.CloseConnectionsOnSwitch {
+		t.Errorf("Expected flags to override environment, got %+v", cfg)
+	}
+}
+
+func TestLoadMissingConfigFile(t *testing.T) {
+	dir := t.TempDir()
+
+	// A missing default config file is not an error
+	cfg, err := Load(nil, envFunc(map[string]string{"XDG_CONFIG_HOME": dir}))
+	if err != nil {
+		t.Fatalf("Expected no error for missing default config, got %v", err)
+	}
+	if cfg != (Config{}) {
+		t.Errorf("Expected zero config, got %+v", cfg)
+	}
+
+	// A missing explicit config file is
+	_, err = Load([]string{"--config", filepath.Join(dir, "nope.json")}, envFunc(nil))
+	if err == nil {
+		t.Errorf("Expected error for missing --config file")
+	}
+}
+
+func TestLoadClientCertPair(t *testing.T) {
+	dir := t.TempDir()
+	env := envFunc(map[string]string{"XDG_CONFIG_HOME": dir})
+
+	for _, args := range [][]string{
+		{"--client-cert", "client.crt"},
+		{"--client-key", "client.key"},
+	} {
+		if _, err := Load(args, env); err == nil {
+			t.Errorf("Expected %v without its pair to be rejected", args)
+		}
+	}
+
+	// The pair may come from different sources
+	writeConfig(t, dir, `{"client-key": "file.key"}`)
+	cfg, err := Load([]string{"--client-cert", "client.crt"}, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ClientCert != "client.crt" || cfg.ClientKey != "file.key" {
+		t.Errorf("Expected cert from flags and key from file, got %+v", cfg)
+	}
+}
+
+func TestMemoryThreshold(t *testing.T) {
+	for in, want := range map[string]ByteSize{
+		"1024":   1024,
+		"512MB":  512 << 20,
+		"1.5GiB": 3 << 29,
+		"64 k":   64 << 10,
+	} {
+		if got, err := ParseByteSize(in); err != nil || got != want {
+			t.Errorf("ParseByteSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	if _, err := ParseByteSize("lots"); err == nil {
+		t.Errorf("Expected error for an invalid size")
+	}
+
+	dir := t.TempDir()
+	writeConfig(t, dir, `{"memory-threshold": "256MB"}`)
+	env := envFunc(map[string]string{"XDG_CONFIG_HOME": dir})
+	cfg, err := Load(nil, env)
+	if err != nil || cfg.MemoryThreshold != 256<<20 {
+		t.Errorf("Expected threshold from config file, got %d, %v", cfg.MemoryThreshold, err)
+	}
+	cfg, err = Load([]string{"--memory-threshold", "1GB"}, env)
+	if err != nil || cfg.MemoryThreshold != 1<<30 {
+		t.Errorf("Expected flag to override config file, got %d, %v", cfg.MemoryThreshold, err)
+	}
+}
@@ -0,0 +1,80 @@
+package fakeclash
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"path/filepath"
+
+	"github.com/wallacegibbon/proxy-controller-tui/internal/clash"
+)
+
+// Config returns the current running configuration.
+func (s *Server) Config() clash.Config {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.config
+}
+
+func (s *Server) handleGetConfig(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, s.config)
+}
+
+// handlePatchConfig applies a partial update. Like Mihomo, modes are case
+// insensitive and anything invalid is "Body invalid".
+func (s *Server) handlePatchConfig(w http.ResponseWriter, r *http.Request) {
+	var patch clash.ConfigPatch
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		writeError(w, http.StatusBadRequest, "Body invalid")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.config.Apply(patch); err != nil {
+		writeError(w, http.StatusBadRequest, "Body invalid")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleReloadConfig restores the initial config and proxies, as if the
+// core read its file again. Like Mihomo, a relative path is refused.
+func (s *Server) handleReloadConfig(w http.ResponseWriter, r *http.Request) {
+	var req clash.ReloadConfigRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Body invalid")
+		return
+	}
+	if req.Path != "" && !filepath.IsAbs(req.Path) {
+		writeError(w, http.StatusBadRequest, "path is not a absolute path")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reset()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleRestart(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reset()
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// reset puts back the state the server was created with. The caller holds
+// s.mu.
+func (s *Server) reset() {
+	s.config = s.initConfig
+	s.proxies = maps.Clone(s.initProxies)
+}
+
+// handleFlushCache answers both cache flushes. There is nothing to flush,
+// Requests shows which one was asked for.
+func (s *Server) handleFlushCache(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
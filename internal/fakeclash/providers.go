@@ -0,0 +1,98 @@
+package fakeclash
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/wallacegibbon/proxy-controller-tui/internal/clash"
+)
+
+// ProxyProvider returns the current state of a proxy provider.
+func (s *Server) ProxyProvider(name string) (clash.ProxyProvider, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.pps[name]
+	return p, ok
+}
+
+func (s *Server) handleGetProxyProviders(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, clash.ProxyProvidersResponse{Providers: s.pps})
+}
+
+func (s *Server) handleGetProxyProvider(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.pps[r.PathValue("name")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Resource not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (s *Server) handleUpdateProxyProvider(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.pps[r.PathValue("name")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Resource not found")
+		return
+	}
+	p.UpdatedAt = time.Now()
+	s.pps[p.Name] = p
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleHealthCheckProxyProvider tests every proxy of the provider with the
+// delays of SetDelay, recording 0 for unreachable ones like Mihomo.
+func (s *Server) handleHealthCheckProxyProvider(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.pps[r.PathValue("name")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Resource not found")
+		return
+	}
+	now := time.Now().Format(time.RFC3339)
+	proxies := make([]clash.Proxy, len(p.Proxies))
+	for i, proxy := range p.Proxies {
+		delay := s.delayOf(proxy.Name)
+		if delay < 0 {
+			delay = 0
+		}
+		proxy.History = append(proxy.History, clash.ProxyHistory{Time: now, Delay: delay})
+		proxies[i] = proxy
+	}
+	p.Proxies = proxies
+	s.pps[p.Name] = p
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RuleProvider returns the current state of a rule provider.
+func (s *Server) RuleProvider(name string) (clash.RuleProvider, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.rps[name]
+	return p, ok
+}
+
+func (s *Server) handleGetRuleProviders(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, clash.RuleProvidersResponse{Providers: s.rps})
+}
+
+func (s *Server) handleUpdateRuleProvider(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.rps[r.PathValue("name")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Resource not found")
+		return
+	}
+	p.UpdatedAt = time.Now()
+	s.rps[p.Name] = p
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,600 @@
+// Package fakeclash is a fake Clash/Mihomo external controller built on
+// net/http. It follows Mihomo's status codes and error bodies closely enough
+// to test clients against, and can inject latency and failures.
+package fakeclash
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wallacegibbon/proxy-controller-tui/internal/clash"
+)
+
+// FaultKind selects how an injected fault fails a request.
+type FaultKind int
+
+const (
+	FaultUnauthorized  FaultKind = iota + 1 // 401 Unauthorized
+	FaultNotFound                           // 404 Resource not found
+	FaultInternal                           // 500 Internal Server Error
+	FaultTimeout                            // never answer, the client has to give up
+	FaultMalformedJSON                      // 200 with a truncated JSON body
+)
+
+// ParseFaultKind accepts "401", "404", "500", "timeout" and "malformed".
+func ParseFaultKind(s string) (FaultKind, bool) {
+	switch s {
+	case "401":
+		return FaultUnauthorized, true
+	case "404":
+		return FaultNotFound, true
+	case "500":
+		return FaultInternal, true
+	case "timeout":
+		return FaultTimeout, true
+	case "malformed":
+		return FaultMalformedJSON, true
+	}
+	return 0, false
+}
+
+// Fault makes matching requests fail instead of being served.
+type Fault struct {
+	Method string // empty matches every method
+	Path   string // path prefix, empty matches every path
+	Kind   FaultKind
+	Times  int // number of requests to fail, 0 means until ClearFaults
+}
+
+func (f Fault) matches(r *http.Request) bool {
+	return (f.Method == "" || f.Method == r.Method) && strings.HasPrefix(r.URL.Path, f.Path)
+}
+
+// Request is an entry of the request log.
+type Request struct {
+	Method      string
+	Path        string // decoded path
+	EscapedPath string // path as sent on the wire
+	Query       url.Values
+	Body        []byte
+	Status      int
+}
+
+// Options configures a Server.
+type Options struct {
+	Proxies     map[string]clash.Proxy // initial state, clash.MockProxies() when nil
+	Connections []clash.Connection
+	Rules       []clash.Rule // clash.MockRules() when nil
+	// ProxyProviders defaults to clash.MockProxyProviders.
+	ProxyProviders map[string]clash.ProxyProvider
+	// RuleProviders defaults to clash.MockRuleProviders. They are served
+	// by Premium and Meta versions only.
+	RuleProviders map[string]clash.RuleProvider
+	// Config answered on /configs, clash.MockConfig() when nil.
+	Config  *clash.Config
+	Secret  string        // required bearer token, none when empty
+	Latency time.Duration // added to every response
+	// Version answered on /version. Meta-only endpoints are left out unless
+	// Meta is set. Defaults to a recent Mihomo.
+	Version *clash.Version
+	// StreamInterval is the period of streaming endpoints such as /traffic,
+	// one second like Mihomo when zero.
+	StreamInterval time.Duration
+}
+
+// Server is a fake controller. It implements http.Handler, so it can be
+// used with httptest.NewServer or http.ListenAndServe.
+type Server struct {
+	mux  *http.ServeMux
+	done chan struct{}
+
+	mu      sync.Mutex
+	proxies map[string]clash.Proxy
+	conns   []clash.Connection
+	rules   []clash.Rule
+	pps     map[string]clash.ProxyProvider
+	rps     map[string]clash.RuleProvider
+	config  clash.Config
+	// initial state restored by a config reload or restart
+	initConfig  clash.Config
+	initProxies map[string]clash.Proxy
+	delays      map[string]int // ms, negative means unreachable
+	secret      string
+	latency     time.Duration
+	faults      []Fault
+	requests    []Request
+
+	streamInterval time.Duration
+	traffic        clash.Traffic
+	memory         clash.MemoryUsage
+	logs           []clash.LogEntry
+}
+
+func New(opts Options) *Server {
+	proxies := opts.Proxies
+	if proxies == nil {
+		proxies = clash.MockProxies()
+	}
+	rules := opts.Rules
+	if rules == nil {
+		rules = clash.MockRules()
+	}
+	s := &Server{
+		mux:     http.NewServeMux(),
+		done:    make(chan struct{}),
+		proxies: make(map[string]clash.Proxy, len(proxies)),
+		conns:   append([]clash.Connection(nil), opts.Connections...),
+		rules:   rules,
+		delays:  make(map[string]int),
+		secret:  opts.Secret,
+		latency: opts.Latency,
+
+		streamInterval: opts.StreamInterval,
+		traffic:        clash.Traffic{Up: 12 << 10, Down: 340 << 10},
+		memory:         clash.MemoryUsage{InUse: 56 << 20},
+	}
+	if s.streamInterval <= 0 {
+		s.streamInterval = time.Second
+	}
+	for name, p := range proxies {
+		s.proxies[name] = p
+	}
+	pps := opts.ProxyProviders
+	if pps == nil {
+		pps = clash.MockProxyProviders(time.Now())
+	}
+	s.pps = make(map[string]clash.ProxyProvider, len(pps))
+	for name, p := range pps {
+		s.pps[name] = p
+	}
+	rps := opts.RuleProviders
+	if rps == nil {
+		rps = clash.MockRuleProviders(time.Now())
+	}
+	s.rps = make(map[string]clash.RuleProvider, len(rps))
+	for name, p := range rps {
+		s.rps[name] = p
+	}
+
+	s.config = clash.MockConfig()
+	if opts.Config != nil {
+		s.config = *opts.Config
+	}
+	s.initConfig = s.config
+	s.initProxies = maps.Clone(s.proxies)
+
+	version := opts.Version
+	if version == nil {
+		version = &clash.Version{Version: "v1.19.0", Meta: true}
+	}
+
+	s.mux.HandleFunc("GET /version", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, version)
+	})
+	s.mux.HandleFunc("GET /proxies", s.handleGetProxies)
+	s.mux.HandleFunc("GET /proxies/{name}", s.handleGetProxy)
+	s.mux.HandleFunc("PUT /proxies/{name}", s.handleSelectProxy)
+	s.mux.HandleFunc("GET /proxies/{name}/delay", s.handleProxyDelay)
+	s.mux.HandleFunc("GET /connections", s.handleGetConnections)
+	s.mux.HandleFunc("DELETE /connections", s.handleCloseAllConnections)
+	s.mux.HandleFunc("DELETE /connections/{id}", s.handleCloseConnection)
+	s.mux.HandleFunc("GET /rules", s.handleGetRules)
+	s.mux.HandleFunc("GET /providers/proxies", s.handleGetProxyProviders)
+	s.mux.HandleFunc("GET /providers/proxies/{name}", s.handleGetProxyProvider)
+	s.mux.HandleFunc("PUT /providers/proxies/{name}", s.handleUpdateProxyProvider)
+	s.mux.HandleFunc("GET /providers/proxies/{name}/healthcheck", s.handleHealthCheckProxyProvider)
+	s.mux.HandleFunc("GET /configs", s.handleGetConfig)
+	s.mux.HandleFunc("PATCH /configs", s.handlePatchConfig)
+	s.mux.HandleFunc("PUT /configs", s.handleReloadConfig)
+	s.mux.HandleFunc("GET /traffic", s.handleTraffic)
+	s.mux.HandleFunc("GET /logs", s.handleLogs)
+	if version.Meta || version.Premium {
+		s.mux.HandleFunc("GET /providers/rules", s.handleGetRuleProviders)
+		s.mux.HandleFunc("PUT /providers/rules/{name}", s.handleUpdateRuleProvider)
+	}
+	if version.Meta {
+		s.mux.HandleFunc("DELETE /proxies/{name}", s.handleUnfixProxy)
+		s.mux.HandleFunc("GET /group/{name}/delay", s.handleGroupDelay)
+		s.mux.HandleFunc("GET /memory", s.handleMemory)
+		s.mux.HandleFunc("POST /restart", s.handleRestart)
+		s.mux.HandleFunc("POST /cache/fakeip/flush", s.handleFlushCache)
+		s.mux.HandleFunc("POST /cache/dns/flush", s.handleFlushCache)
+	}
+	return s
+}
+
+// Close releases requests held by FaultTimeout.
+func (s *Server) Close() {
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+	}
+}
+
+// SetLatency changes the delay added to every response.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// SetDelay fixes the delay test result of a proxy in milliseconds. A
+// negative value makes the proxy unreachable (503).
+func (s *Server) SetDelay(proxyName string, ms int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delays[proxyName] = ms
+}
+
+// SetConnections replaces the tracked connections.
+func (s *Server) SetConnections(conns []clash.Connection) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conns = append([]clash.Connection(nil), conns...)
+}
+
+// Connections returns the tracked connections.
+func (s *Server) Connections() []clash.Connection {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]clash.Connection(nil), s.conns...)
+}
+
+// Inject adds a fault. Faults are checked in the order they were added.
+func (s *Server) Inject(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, f)
+}
+
+// ClearFaults removes every injected fault.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// Requests returns a copy of the request log.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// ResetRequests empties the request log.
+func (s *Server) ResetRequests() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = nil
+}
+
+// Proxy returns the current state of a proxy or group.
+func (s *Server) Proxy(name string) (clash.Proxy, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.proxies[name]
+	return p, ok
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	defer func() {
+		s.mu.Lock()
+		s.requests = append(s.requests, Request{
+			Method:      r.Method,
+			Path:        r.URL.Path,
+			EscapedPath: r.URL.EscapedPath(),
+			Query:       r.URL.Query(),
+			Body:        body,
+			Status:      rec.status,
+		})
+		s.mu.Unlock()
+	}()
+
+	s.mu.Lock()
+	latency := s.latency
+	fault, faulted := s.takeFault(r)
+	s.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		case <-s.done:
+			return
+		}
+	}
+
+	if faulted {
+		s.serveFault(rec, r, fault)
+		return
+	}
+
+	if !s.authorized(r) {
+		writeError(rec, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	s.mux.ServeHTTP(rec, r)
+}
+
+// takeFault returns the first fault matching r, consuming one use of it.
+// Callers must hold s.mu.
+func (s *Server) takeFault(r *http.Request) (Fault, bool) {
+	for i, f := range s.faults {
+		if !f.matches(r) {
+			continue
+		}
+		if f.Times > 0 {
+			s.faults[i].Times--
+			if s.faults[i].Times == 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+		}
+		return f, true
+	}
+	return Fault{}, false
+}
+
+func (s *Server) serveFault(w http.ResponseWriter, r *http.Request, f Fault) {
+	switch f.Kind {
+	case FaultUnauthorized:
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+	case FaultNotFound:
+		writeError(w, http.StatusNotFound, "Resource not found")
+	case FaultTimeout:
+		select {
+		case <-r.Context().Done():
+		case <-s.done:
+		}
+	case FaultMalformedJSON:
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"proxies": {"GLOBAL": {"name": `))
+	default:
+		writeError(w, http.StatusInternalServerError, "Internal Server Error")
+	}
+}
+
+// authorized mirrors Mihomo: a bearer token, or ?token= for websockets.
+func (s *Server) authorized(r *http.Request) bool {
+	s.mu.Lock()
+	secret := s.secret
+	s.mu.Unlock()
+	if secret == "" {
+		return true
+	}
+	if r.Header.Get("Authorization") == "Bearer "+secret {
+		return true
+	}
+	return r.URL.Query().Get("token") == secret
+}
+
+func (s *Server) handleGetProxies(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, clash.ProxiesResponse{Proxies: s.proxies})
+}
+
+func (s *Server) handleGetConnections(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp := clash.ConnectionsResponse{Connections: s.conns}
+	for _, c := range s.conns {
+		resp.UploadTotal += c.Upload
+		resp.DownloadTotal += c.Download
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleGetRules(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, clash.RulesResponse{Rules: s.rules})
+}
+
+func (s *Server) handleCloseConnection(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Unknown ids are not an error, the connection may just have ended
+	for i, c := range s.conns {
+		if c.ID == r.PathValue("id") {
+			s.conns = append(s.conns[:i], s.conns[i+1:]...)
+			break
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleCloseAllConnections(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conns = nil
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleGetProxy(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.proxies[r.PathValue("name")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Resource not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (s *Server) handleSelectProxy(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Body invalid")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	group, ok := s.proxies[r.PathValue("name")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Resource not found")
+		return
+	}
+	if !isSelectable(group.Type) {
+		writeError(w, http.StatusBadRequest, "Must be a Selector")
+		return
+	}
+	if !contains(group.All, req.Name) {
+		writeError(w, http.StatusBadRequest, "Selector update error: proxy not exist")
+		return
+	}
+	group.Now = req.Name
+	// Selecting in an auto group pins it until DELETE, like Mihomo
+	if group.Type != "Selector" {
+		group.Fixed = req.Name
+	}
+	s.proxies[group.Name] = group
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleUnfixProxy(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	group, ok := s.proxies[r.PathValue("name")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Resource not found")
+		return
+	}
+	// Mihomo only unfixes auto groups, plain selectors are a bad request
+	if !isSelectable(group.Type) || group.Type == "Selector" {
+		writeError(w, http.StatusBadRequest, "Body invalid")
+		return
+	}
+	group.Fixed = ""
+	s.proxies[group.Name] = group
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleProxyDelay(w http.ResponseWriter, r *http.Request) {
+	timeout, ok := parseDelayQuery(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "Body invalid")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name := r.PathValue("name")
+	if _, ok := s.proxies[name]; !ok {
+		writeError(w, http.StatusNotFound, "Resource not found")
+		return
+	}
+	delay := s.delayOf(name)
+	switch {
+	case delay < 0:
+		writeError(w, http.StatusServiceUnavailable, "An error occurred in the delay test")
+	case delay > timeout:
+		writeError(w, http.StatusGatewayTimeout, "Timeout")
+	default:
+		writeJSON(w, http.StatusOK, map[string]int{"delay": delay})
+	}
+}
+
+func (s *Server) handleGroupDelay(w http.ResponseWriter, r *http.Request) {
+	timeout, ok := parseDelayQuery(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "Body invalid")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	group, ok := s.proxies[r.PathValue("name")]
+	if !ok || len(group.All) == 0 {
+		writeError(w, http.StatusNotFound, "Resource not found")
+		return
+	}
+	// Only members that passed the test are reported
+	result := make(map[string]int)
+	for _, name := range group.All {
+		if delay := s.delayOf(name); delay >= 0 && delay <= timeout {
+			result[name] = delay
+		}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// delayOf returns the configured delay or clash.MockDelay, the one the
+// mock backend reports. Callers must hold s.mu.
+func (s *Server) delayOf(name string) int {
+	if d, ok := s.delays[name]; ok {
+		return d
+	}
+	return clash.MockDelay(name)
+}
+
+func parseDelayQuery(r *http.Request) (timeout int, ok bool) {
+	q := r.URL.Query()
+	timeout, err := strconv.Atoi(q.Get("timeout"))
+	if err != nil || q.Get("url") == "" {
+		return 0, false
+	}
+	return timeout, true
+}
+
+func isSelectable(groupType string) bool {
+	return groupType == "Selector" || groupType == "URLTest" || groupType == "Fallback"
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"message": message})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach Flush on the real writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Hijack hands the connection over for a WebSocket upgrade.
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	r.status = http.StatusSwitchingProtocols
+	return http.NewResponseController(r.ResponseWriter).Hijack()
+}
@@ -0,0 +1,533 @@
+package fakeclash
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/wallacegibbon/proxy-controller-tui/internal/clash"
+)
+
+func newTestClient(t *testing.T, opts Options, clientOpts clash.Options) (*Server, *clash.Client) {
+	t.Helper()
+	fake := New(opts)
+	srv := httptest.NewServer(fake)
+	t.Cleanup(func() {
+		fake.Close()
+		srv.Close()
+	})
+	clientOpts.BaseURL = srv.URL
+	c, err := clash.NewClient(clientOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fake, c
+}
+
+func TestSelectAndUnfix(t *testing.T) {
+	fake, c := newTestClient(t, Options{}, clash.Options{})
+
+	if err := c.SelectProxy("Proxy Group B", "Auto-5"); err != nil {
+		t.Fatalf("SelectProxy: %v", err)
+	}
+	if g, _ := fake.Proxy("Proxy Group B"); g.Now != "Auto-5" || g.Fixed != "Auto-5" {
+		t.Errorf("Expected URLTest group to be pinned to Auto-5, got now=%q fixed=%q", g.Now, g.Fixed)
+	}
+
+	if err := c.ResetFixedProxy("Proxy Group B"); err != nil {
+		t.Fatalf("ResetFixedProxy: %v", err)
+	}
+	if g, _ := fake.Proxy("Proxy Group B"); g.Fixed != "" {
+		t.Errorf("Expected fixed to be cleared, got %q", g.Fixed)
+	}
+
+	if err := c.SelectProxy("Proxy Group A", "Auto-1"); err == nil {
+		t.Errorf("Expected error selecting a proxy outside the group")
+	}
+	if err := c.ResetFixedProxy("Proxy Group A"); err == nil {
+		t.Errorf("Expected error unfixing a plain Selector")
+	}
+
+	reqs := fake.Requests()
+	if len(reqs) != 4 {
+		t.Fatalf("Expected 4 logged requests, got %d", len(reqs))
+	}
+	if reqs[0].Method != http.MethodPut || reqs[0].Path != "/proxies/Proxy Group B" || reqs[0].Status != http.StatusNoContent {
+		t.Errorf("Unexpected first request %+v", reqs[0])
+	}
+	if reqs[2].Status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for proxy not in group, got %d", reqs[2].Status)
+	}
+}
+
+func TestDelayEndpoints(t *testing.T) {
+	fake, c := newTestClient(t, Options{}, clash.Options{})
+	fake.SetDelay("Proxy-1", 80)
+	fake.SetDelay("Proxy-2", -1)
+	fake.SetDelay("Proxy-3", 9000)
+
+	opts := clash.DelayOptions{Timeout: 5 * time.Second}
+	for name, want := range map[string]clash.DelayStatus{
+		"Proxy-1": clash.DelayOK,
+		"Proxy-2": clash.DelayUnreachable,
+		"Proxy-3": clash.DelayTimeout,
+	} {
+		res, err := c.ProxyDelay(name, opts)
+		if err != nil {
+			t.Fatalf("ProxyDelay(%s): %v", name, err)
+		}
+		if res.Status != want {
+			t.Errorf("ProxyDelay(%s): expected %v, got %v", name, want, res.Status)
+		}
+	}
+
+	delays, err := c.GroupDelay("Proxy Group A", opts)
+	if err != nil {
+		t.Fatalf("GroupDelay: %v", err)
+	}
+	if delays["Proxy-1"] != 80 {
+		t.Errorf("Expected Proxy-1 at 80ms, got %v", delays)
+	}
+	if _, ok := delays["Proxy-2"]; ok {
+		t.Errorf("Failed proxies must be left out of group results, got %v", delays)
+	}
+
+	// Proxies without a set delay report what the mock backend does
+	mock, _ := clash.NewMemoryBackend(clash.MockProxies()).GroupDelayContext(context.Background(), "Proxy Group A", opts)
+	if delays["Proxy-4"] == 0 || delays["Proxy-4"] != mock["Proxy-4"] {
+		t.Errorf("Expected Proxy-4 at %dms like the mock backend, got %v", mock["Proxy-4"], delays)
+	}
+}
+
+func TestFaultInjection(t *testing.T) {
+	fake, c := newTestClient(t, Options{Secret: "s3cret"}, clash.Options{Secret: "s3cret", Timeout: 200 * time.Millisecond})
+
+	fake.Inject(Fault{Path: "/proxies", Kind: FaultInternal, Times: 1})
+	if _, err := c.GetProxies(); err == nil {
+		t.Errorf("Expected injected 500")
+	}
+	if _, err := c.GetProxies(); err != nil {
+		t.Errorf("Expected fault to be used up, got %v", err)
+	}
+
+	fake.Inject(Fault{Kind: FaultMalformedJSON, Times: 1})
+	if _, err := c.GetProxies(); err == nil {
+		t.Errorf("Expected decode error for malformed JSON")
+	}
+
+	fake.Inject(Fault{Kind: FaultTimeout, Times: 1})
+	if _, err := c.GetProxies(); err == nil {
+		t.Errorf("Expected timeout")
+	}
+
+	fake.SetLatency(500 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.GetProxiesContext(ctx); err == nil {
+		t.Errorf("Expected latency to exceed the deadline")
+	}
+	fake.SetLatency(0)
+
+	_, wrongSecret := newTestClient(t, Options{Secret: "s3cret"}, clash.Options{Secret: "nope"})
+	if _, err := wrongSecret.GetProxies(); err == nil {
+		t.Errorf("Expected 401 for a wrong secret")
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	fake, c := newTestClient(t, Options{Secret: "s3cret"}, clash.Options{Secret: "s3cret"})
+
+	var notInGroup *clash.ProxyNotInGroupError
+	if err := c.SelectProxy("Proxy Group A", "Auto-1"); !errors.As(err, &notInGroup) {
+		t.Errorf("Expected ProxyNotInGroupError, got %v", err)
+	}
+
+	var noGroup *clash.GroupNotFoundError
+	if err := c.SelectProxy("Nope", "Proxy-1"); !errors.As(err, &noGroup) || noGroup.Group != "Nope" {
+		t.Errorf("Expected GroupNotFoundError, got %v", err)
+	}
+
+	var decode *clash.DecodeError
+	fake.Inject(Fault{Kind: FaultMalformedJSON, Times: 1})
+	if _, err := c.GetProxies(); !errors.As(err, &decode) {
+		t.Errorf("Expected DecodeError, got %v", err)
+	}
+
+	var unauthorized *clash.UnauthorizedError
+	fake.Inject(Fault{Kind: FaultUnauthorized, Times: 1})
+	if _, err := c.GetProxies(); !errors.As(err, &unauthorized) {
+		t.Errorf("Expected UnauthorizedError, got %v", err)
+	}
+
+	var status *clash.StatusError
+	fake.Inject(Fault{Kind: FaultInternal, Times: 1})
+	if _, err := c.GetProxies(); !errors.As(err, &status) || status.StatusCode != 500 {
+		t.Errorf("Expected StatusError 500, got %v", err)
+	}
+
+	// Nothing listens on a closed server's address
+	srv := httptest.NewServer(New(Options{}))
+	srv.Close()
+	dead, err := clash.NewClient(clash.Options{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var unreachable *clash.UnreachableError
+	if _, err := dead.GetProxies(); !errors.As(err, &unreachable) || !errors.Is(err, syscall.ECONNREFUSED) {
+		t.Errorf("Expected UnreachableError wrapping ECONNREFUSED, got %v", err)
+	}
+}
+
+func TestCloseConnections(t *testing.T) {
+	conns := []clash.Connection{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	fake, c := newTestClient(t, Options{Connections: conns}, clash.Options{})
+
+	resp, err := c.GetConnections()
+	if err != nil || len(resp.Connections) != 3 {
+		t.Fatalf("Expected 3 connections, got %v (%v)", resp, err)
+	}
+
+	if err := c.CloseConnection("b"); err != nil {
+		t.Fatalf("CloseConnection: %v", err)
+	}
+	if err := c.CloseConnection("gone"); err != nil {
+		t.Errorf("Expected closing an unknown id to succeed, got %v", err)
+	}
+	if got := fake.Connections(); len(got) != 2 || got[0].ID != "a" || got[1].ID != "c" {
+		t.Errorf("Expected a and c to remain, got %+v", got)
+	}
+
+	if err := c.CloseAllConnections(); err != nil {
+		t.Fatalf("CloseAllConnections: %v", err)
+	}
+	if got := fake.Connections(); len(got) != 0 {
+		t.Errorf("Expected no connections, got %+v", got)
+	}
+}
+
+func TestTrafficStream(t *testing.T) {
+	for _, ws := range []bool{false, true} {
+		fake, c := newTestClient(t,
+			Options{Secret: "s3cret", StreamInterval: 10 * time.Millisecond},
+			clash.Options{Secret: "s3cret", WebSocket: ws})
+		fake.SetTraffic(clash.Traffic{Up: 100, Down: 200})
+
+		stream, err := c.StreamTraffic()
+		if err != nil {
+			t.Fatalf("websocket=%v: StreamTraffic: %v", ws, err)
+		}
+		for i := 0; i < 3; i++ {
+			got, err := stream.Next()
+			if err != nil {
+				t.Fatalf("websocket=%v: Next: %v", ws, err)
+			}
+			if got.Up != 100 || got.Down != 200 {
+				t.Errorf("websocket=%v: unexpected sample %+v", ws, got)
+			}
+		}
+		stream.Close()
+		if _, err := stream.Next(); err == nil {
+			t.Errorf("websocket=%v: expected an error after Close", ws)
+		}
+
+		// WebSocket clients authenticate with ?token=. Streams are logged
+		// once the handler notices the client is gone.
+		var reqs []Request
+		for deadline := time.Now().Add(time.Second); len(reqs) == 0 && time.Now().Before(deadline); {
+			time.Sleep(10 * time.Millisecond)
+			reqs = fake.Requests()
+		}
+		if ws && (len(reqs) == 0 || reqs[0].Query.Get("token") != "s3cret") {
+			t.Errorf("Expected the secret as token query, got %+v", reqs)
+		}
+	}
+
+	_, c := newTestClient(t, Options{Secret: "s3cret"}, clash.Options{WebSocket: true})
+	var unauthorized *clash.UnauthorizedError
+	if _, err := c.StreamTraffic(); !errors.As(err, &unauthorized) {
+		t.Errorf("Expected UnauthorizedError without the secret, got %v", err)
+	}
+}
+
+func TestMemoryStream(t *testing.T) {
+	fake, c := newTestClient(t, Options{StreamInterval: 10 * time.Millisecond}, clash.Options{})
+	fake.SetMemory(clash.MemoryUsage{InUse: 64 << 20})
+	stream, err := c.StreamMemory()
+	if err != nil {
+		t.Fatalf("StreamMemory: %v", err)
+	}
+	defer stream.Close()
+	if got, err := stream.Next(); err != nil || got.InUse != 64<<20 {
+		t.Errorf("Expected 64 MB in use, got %+v, %v", got, err)
+	}
+
+	// Plain Clash has no /memory
+	_, c = newTestClient(t, Options{Version: &clash.Version{Version: "v1.18.0"}}, clash.Options{})
+	var status *clash.StatusError
+	if _, err := c.StreamMemory(); !errors.As(err, &status) || status.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 from a non-Meta core, got %v", err)
+	}
+}
+
+func TestLogsStream(t *testing.T) {
+	fake, c := newTestClient(t, Options{StreamInterval: 10 * time.Millisecond}, clash.Options{})
+	fake.Log(clash.LogError, "before subscribing")
+
+	stream, err := c.StreamLogs(clash.LogWarning)
+	if err != nil {
+		t.Fatalf("StreamLogs: %v", err)
+	}
+	defer stream.Close()
+	fake.Log(clash.LogInfo, "too verbose")
+	fake.Log(clash.LogWarning, "dial error")
+
+	got, err := stream.Next()
+	if err != nil || got.Type != clash.LogWarning || got.Payload != "dial error" {
+		t.Errorf("Expected only the new warning, got %+v, %v", got, err)
+	}
+
+	var status *clash.StatusError
+	if _, err := c.StreamLogs("loud"); !errors.As(err, &status) || status.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown level, got %v", err)
+	}
+}
+
+func TestGetRules(t *testing.T) {
+	hit := time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC)
+	_, c := newTestClient(t, Options{Rules: []clash.Rule{
+		{Index: 0, Type: "RuleSet", Payload: "streaming", Proxy: "Proxy Group A", Size: 1532,
+			Extra: &clash.RuleExtra{HitCount: 7, HitAt: hit}},
+		{Index: 1, Type: "Match", Proxy: "DIRECT", Size: -1},
+	}}, clash.Options{})
+
+	resp, err := c.GetRules()
+	if err != nil {
+		t.Fatalf("GetRules: %v", err)
+	}
+	if len(resp.Rules) != 2 {
+		t.Fatalf("Expected 2 rules, got %d", len(resp.Rules))
+	}
+	r := resp.Rules[0]
+	if r.Type != "RuleSet" || r.Proxy != "Proxy Group A" || r.Size != 1532 || r.Extra == nil || r.Extra.HitCount != 7 || !r.Extra.HitAt.Equal(hit) {
+		t.Errorf("Unexpected rule %+v", r)
+	}
+	if resp.Rules[1].Extra != nil {
+		t.Errorf("Expected no extra fields on the second rule")
+	}
+}
+
+func TestProxyProviders(t *testing.T) {
+	stale := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	fake, c := newTestClient(t, Options{ProxyProviders: map[string]clash.ProxyProvider{
+		"sub": {Name: "sub", Type: "Proxy", VehicleType: "HTTP", UpdatedAt: stale,
+			Proxies:          []clash.Proxy{{Name: "Proxy-1"}, {Name: "Proxy-2"}},
+			SubscriptionInfo: &clash.SubscriptionInfo{Download: 5 << 30, Total: 100 << 30, Expire: 1800000000}},
+	}}, clash.Options{})
+	fake.SetDelay("Proxy-2", -1)
+
+	resp, err := c.GetProxyProviders()
+	if err != nil {
+		t.Fatalf("GetProxyProviders: %v", err)
+	}
+	p, ok := resp.Providers["sub"]
+	if !ok || p.VehicleType != "HTTP" || len(p.Proxies) != 2 || p.SubscriptionInfo == nil || p.SubscriptionInfo.Total != 100<<30 {
+		t.Fatalf("Unexpected providers %+v", resp.Providers)
+	}
+
+	if err := c.UpdateProxyProvider("sub"); err != nil {
+		t.Fatalf("UpdateProxyProvider: %v", err)
+	}
+	if p, _ := fake.ProxyProvider("sub"); !p.UpdatedAt.After(stale) {
+		t.Errorf("Expected updatedAt to move, got %v", p.UpdatedAt)
+	}
+
+	if err := c.HealthCheckProxyProvider("sub"); err != nil {
+		t.Fatalf("HealthCheckProxyProvider: %v", err)
+	}
+	p, _ = fake.ProxyProvider("sub")
+	if h := p.Proxies[0].History; len(h) != 1 || h[0].Delay <= 0 {
+		t.Errorf("Expected a delay for Proxy-1, got %+v", h)
+	}
+	if h := p.Proxies[1].History; len(h) != 1 || h[0].Delay != 0 {
+		t.Errorf("Expected a failed check for Proxy-2, got %+v", h)
+	}
+
+	var notFound *clash.ProviderNotFoundError
+	if err := c.UpdateProxyProvider("nope"); !errors.As(err, &notFound) || notFound.Provider != "nope" {
+		t.Errorf("Expected ProviderNotFoundError, got %v", err)
+	}
+	if err := c.HealthCheckProxyProvider("nope"); !errors.As(err, &notFound) {
+		t.Errorf("Expected ProviderNotFoundError, got %v", err)
+	}
+}
+
+func TestRuleProviders(t *testing.T) {
+	stale := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	fake, c := newTestClient(t, Options{RuleProviders: map[string]clash.RuleProvider{
+		"ads": {Name: "ads", Type: "Rule", Behavior: "Domain", Format: "MrsRule", RuleCount: 48210, UpdatedAt: stale, VehicleType: "HTTP"},
+	}}, clash.Options{})
+
+	resp, err := c.GetRuleProviders()
+	if err != nil {
+		t.Fatalf("GetRuleProviders: %v", err)
+	}
+	if p, ok := resp.Providers["ads"]; !ok || p.Behavior != "Domain" || p.Format != "MrsRule" || p.RuleCount != 48210 || !p.UpdatedAt.Equal(stale) {
+		t.Fatalf("Unexpected providers %+v", resp.Providers)
+	}
+
+	if err := c.UpdateRuleProvider("ads"); err != nil {
+		t.Fatalf("UpdateRuleProvider: %v", err)
+	}
+	if p, _ := fake.RuleProvider("ads"); !p.UpdatedAt.After(stale) {
+		t.Errorf("Expected updatedAt to move, got %v", p.UpdatedAt)
+	}
+	var notFound *clash.ProviderNotFoundError
+	if err := c.UpdateRuleProvider("nope"); !errors.As(err, &notFound) {
+		t.Errorf("Expected ProviderNotFoundError, got %v", err)
+	}
+
+	// Open source Clash has no rule providers
+	_, plain := newTestClient(t, Options{Version: &clash.Version{Version: "v1.18.0"}}, clash.Options{})
+	var status *clash.StatusError
+	if _, err := plain.GetRuleProviders(); !errors.As(err, &status) || status.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 from Clash, got %v", err)
+	}
+}
+
+func TestConfigMode(t *testing.T) {
+	fake, c := newTestClient(t, Options{}, clash.Options{})
+
+	cfg, err := c.GetConfig()
+	if err != nil {
+		t.Fatalf("GetConfig: %v", err)
+	}
+	if cfg.Mode != clash.ModeRule || cfg.MixedPort != 7890 {
+		t.Errorf("Unexpected config %+v", cfg)
+	}
+
+	mode := "Global"
+	if err := c.PatchConfig(clash.ConfigPatch{Mode: &mode}); err != nil {
+		t.Fatalf("PatchConfig: %v", err)
+	}
+	if got := fake.Config().Mode; got != clash.ModeGlobal {
+		t.Errorf("Expected global mode, got %q", got)
+	}
+
+	mode = "script"
+	var status *clash.StatusError
+	if err := c.PatchConfig(clash.ConfigPatch{Mode: &mode}); !errors.As(err, &status) || status.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown mode, got %v", err)
+	}
+	reqs := fake.Requests()
+	if last := reqs[len(reqs)-1]; last.Method != http.MethodPatch || last.Path != "/configs" {
+		t.Errorf("Expected PATCH /configs, got %+v", last)
+	}
+}
+
+func TestPatchConfig(t *testing.T) {
+	fake, c := newTestClient(t, Options{}, clash.Options{})
+
+	allowLan, level, port, tun := true, clash.LogSilent, 1080, true
+	err := c.PatchConfig(clash.ConfigPatch{
+		AllowLan:  &allowLan,
+		LogLevel:  &level,
+		SocksPort: &port,
+		Tun:       &clash.TunPatch{Enable: &tun},
+	})
+	if err != nil {
+		t.Fatalf("PatchConfig: %v", err)
+	}
+	cfg, err := c.GetConfig()
+	if err != nil {
+		t.Fatalf("GetConfig: %v", err)
+	}
+	if !cfg.AllowLan || cfg.LogLevel != clash.LogSilent || cfg.SocksPort != 1080 || cfg.MixedPort != 7890 || cfg.Tun == nil || !cfg.Tun.Enable || cfg.Tun.Stack != "mixed" {
+		t.Errorf("Unexpected config %+v (tun %+v)", cfg, cfg.Tun)
+	}
+
+	// An invalid field rejects the whole patch
+	allowLan, port = false, 70000
+	var status *clash.StatusError
+	if err := c.PatchConfig(clash.ConfigPatch{AllowLan: &allowLan, MixedPort: &port}); !errors.As(err, &status) || status.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for port 70000, got %v", err)
+	}
+	if got := fake.Config(); !got.AllowLan || got.MixedPort != 7890 {
+		t.Errorf("Expected the config unchanged, got %+v", got)
+	}
+}
+
+func TestReloadAndRestart(t *testing.T) {
+	fake, c := newTestClient(t, Options{}, clash.Options{})
+
+	mode := clash.ModeDirect
+	if err := c.PatchConfig(clash.ConfigPatch{Mode: &mode}); err != nil {
+		t.Fatalf("PatchConfig: %v", err)
+	}
+	if err := c.SelectProxy("Proxy Group A", "Proxy-3"); err != nil {
+		t.Fatalf("SelectProxy: %v", err)
+	}
+
+	if err := c.ReloadConfig(clash.ReloadConfigRequest{Path: "/etc/mihomo/config.yaml"}); err != nil {
+		t.Fatalf("ReloadConfig: %v", err)
+	}
+	reqs := fake.Requests()
+	if last := reqs[len(reqs)-1]; last.Method != http.MethodPut || last.Path != "/configs" || last.Query.Get("force") != "true" || !bytes.Contains(last.Body, []byte(`"path":"/etc/mihomo/config.yaml"`)) {
+		t.Errorf("Expected PUT /configs?force=true, got %+v", last)
+	}
+	if cfg := fake.Config(); cfg.Mode != clash.ModeRule {
+		t.Errorf("Expected the reload to drop runtime changes, got mode %q", cfg.Mode)
+	}
+	if resp, err := c.GetProxies(); err != nil || resp.Proxies["Proxy Group A"].Now != "Proxy-1" {
+		t.Errorf("Expected the selection reset, got %v", err)
+	}
+
+	var status *clash.StatusError
+	if err := c.ReloadConfig(clash.ReloadConfigRequest{Path: "config.yaml"}); !errors.As(err, &status) || status.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a relative path, got %v", err)
+	}
+	if err := c.ReloadConfig(clash.ReloadConfigRequest{Payload: "mode: rule\n"}); err != nil {
+		t.Errorf("ReloadConfig with payload: %v", err)
+	}
+
+	if err := c.PatchConfig(clash.ConfigPatch{Mode: &mode}); err != nil {
+		t.Fatalf("PatchConfig: %v", err)
+	}
+	if err := c.Restart(); err != nil {
+		t.Fatalf("Restart: %v", err)
+	}
+	if cfg := fake.Config(); cfg.Mode != clash.ModeRule {
+		t.Errorf("Expected the restart to drop runtime changes, got mode %q", cfg.Mode)
+	}
+
+	// Plain Clash cannot restart
+	_, plain := newTestClient(t, Options{Version: &clash.Version{Version: "v1.18.0"}}, clash.Options{})
+	if err := plain.Restart(); !errors.As(err, &status) || status.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 from Clash, got %v", err)
+	}
+}
+
+func TestFlushCache(t *testing.T) {
+	fake, c := newTestClient(t, Options{}, clash.Options{})
+
+	if err := c.FlushFakeIPCache(); err != nil {
+		t.Fatalf("FlushFakeIPCache: %v", err)
+	}
+	if err := c.FlushDNSCache(); err != nil {
+		t.Fatalf("FlushDNSCache: %v", err)
+	}
+	reqs := fake.Requests()
+	if len(reqs) != 2 || reqs[0].Method != http.MethodPost || reqs[0].Path != "/cache/fakeip/flush" || reqs[1].Path != "/cache/dns/flush" {
+		t.Errorf("Unexpected requests %+v", reqs)
+	}
+
+	_, plain := newTestClient(t, Options{Version: &clash.Version{Version: "v1.18.0"}}, clash.Options{})
+	var status *clash.StatusError
+	if err := plain.FlushFakeIPCache(); !errors.As(err, &status) || status.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 from Clash, got %v", err)
+	}
+}
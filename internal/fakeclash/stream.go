@@ -0,0 +1,177 @@
+package fakeclash
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/wallacegibbon/proxy-controller-tui/internal/clash"
+	"github.com/wallacegibbon/proxy-controller-tui/internal/websocket"
+)
+
+// SetTraffic changes the sample sent on /traffic.
+func (s *Server) SetTraffic(t clash.Traffic) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.traffic = t
+}
+
+// SetMemory changes the sample sent on /memory.
+func (s *Server) SetMemory(m clash.MemoryUsage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.memory = m
+}
+
+func (s *Server) handleMemory(w http.ResponseWriter, r *http.Request) {
+	s.serveStream(w, r, func() any {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.memory
+	})
+}
+
+// Log adds a line to the /logs stream. Only clients already streaming see
+// it, like Mihomo.
+func (s *Server) Log(level, payload string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logs = append(s.logs, clash.LogEntry{Type: level, Payload: payload})
+}
+
+func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
+	level := r.URL.Query().Get("level")
+	if level == "" {
+		level = clash.LogInfo
+	}
+	min := clash.LogLevelRank(level)
+	if min < 0 {
+		writeError(w, http.StatusBadRequest, "Body invalid")
+		return
+	}
+
+	s.mu.Lock()
+	next := len(s.logs)
+	s.mu.Unlock()
+	s.serveEvents(w, r, func() []any {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		var out []any
+		for _, e := range s.logs[next:] {
+			if clash.LogLevelRank(e.Type) >= min {
+				out = append(out, e)
+			}
+		}
+		next = len(s.logs)
+		return out
+	})
+}
+
+func (s *Server) handleTraffic(w http.ResponseWriter, r *http.Request) {
+	s.serveStream(w, r, func() any {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.traffic
+	})
+}
+
+// serveStream sends sample() right away and then every stream interval.
+func (s *Server) serveStream(w http.ResponseWriter, r *http.Request, sample func() any) {
+	s.serveEvents(w, r, func() []any { return []any{sample()} })
+}
+
+// serveEvents sends what poll returns right away and then every stream
+// interval, like Mihomo: as WebSocket text messages when the client asks
+// for an upgrade, otherwise as chunked JSON lines.
+func (s *Server) serveEvents(w http.ResponseWriter, r *http.Request, poll func() []any) {
+	s.mu.Lock()
+	interval := s.streamInterval
+	s.mu.Unlock()
+
+	if isWebSocketUpgrade(r) {
+		conn, brw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer conn.Close()
+		s.serveWebSocketEvents(conn, brw, r, interval, poll)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+	enc := json.NewEncoder(w)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, v := range poll() {
+			if err := enc.Encode(v); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-r.Context().Done():
+			return
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *Server) serveWebSocketEvents(conn net.Conn, brw *bufio.ReadWriter, r *http.Request, interval time.Duration, poll func() []any) {
+	brw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + websocket.AcceptKey(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+	if err := brw.Flush(); err != nil {
+		return
+	}
+
+	ws := websocket.NewConn(brw.Reader, conn, false)
+	// Reading answers pings and notices the client going away
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			if _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, v := range poll() {
+			b, err := json.Marshal(v)
+			if err != nil {
+				return
+			}
+			if err := ws.WriteMessage(websocket.OpText, append(b, '\n')); err != nil {
+				return
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-gone:
+			return
+		case <-s.done:
+			ws.Close()
+			return
+		}
+	}
+}
+
+func isWebSocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") &&
+		strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
+}
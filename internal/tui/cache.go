@@ -0,0 +1,45 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/wallacegibbon/proxy-controller-tui/internal/clash"
+)
+
+// cacheFlushedMsg reports a cache flush. Either way the outcome goes to a
+// notice, never the error screen.
+type cacheFlushedMsg struct {
+	cache string // "fake-IP" or "DNS"
+	err   error
+}
+
+func flushFakeIPCacheCmd(backend clash.Backend) tea.Cmd {
+	return func() tea.Msg {
+		err := backend.FlushFakeIPCacheContext(context.Background())
+		return cacheFlushedMsg{cache: "fake-IP", err: err}
+	}
+}
+
+func flushDNSCacheCmd(backend clash.Backend) tea.Cmd {
+	return func() tea.Msg {
+		err := backend.FlushDNSCacheContext(context.Background())
+		return cacheFlushedMsg{cache: "DNS", err: err}
+	}
+}
+
+// flushCache runs a flush command if the core supports it.
+func (m Model) flushCache(cmd func(clash.Backend) tea.Cmd) (Model, tea.Cmd) {
+	if !m.caps().FlushCache {
+		return m, m.unsupported("Cache flushing")
+	}
+	return m, cmd(m.Backend)
+}
+
+func (m Model) handleCacheFlushed(msg cacheFlushedMsg) (Model, tea.Cmd) {
+	if msg.err != nil {
+		return m, m.setNotice(fmt.Sprintf("Flushing the %s cache failed: %v", msg.cache, msg.err), true)
+	}
+	return m, m.setNotice("Flushed the "+msg.cache+" cache", false)
+}
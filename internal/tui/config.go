@@ -0,0 +1,339 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/wallacegibbon/proxy-controller-tui/internal/clash"
+)
+
+var (
+	diffRemovedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	diffAddedStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+)
+
+type fieldKind int
+
+const (
+	fieldBool fieldKind = iota
+	fieldEnum
+	fieldPort
+)
+
+// configField is a setting of the Config page. Values are handled as the
+// strings shown on screen.
+type configField struct {
+	key     string // name in the core's config
+	kind    fieldKind
+	options []string // values of an enum field, in cycling order
+	// value reads the field, false when the core does not report it
+	value func(clash.Config) (string, bool)
+	// apply sets the field in a patch; the value is already validated
+	apply func(*clash.ConfigPatch, string)
+}
+
+var configFields = []configField{
+	{
+		key:   "allow-lan",
+		kind:  fieldBool,
+		value: func(c clash.Config) (string, bool) { return strconv.FormatBool(c.AllowLan), true },
+		apply: func(p *clash.ConfigPatch, v string) { b := v == "true"; p.AllowLan = &b },
+	},
+	{
+		key:     "log-level",
+		kind:    fieldEnum,
+		options: clash.LogLevels,
+		value:   func(c clash.Config) (string, bool) { return c.LogLevel, true },
+		apply:   func(p *clash.ConfigPatch, v string) { p.LogLevel = &v },
+	},
+	{
+		key:   "ipv6",
+		kind:  fieldBool,
+		value: func(c clash.Config) (string, bool) { return strconv.FormatBool(c.IPv6), true },
+		apply: func(p *clash.ConfigPatch, v string) { b := v == "true"; p.IPv6 = &b },
+	},
+	{
+		key:  "tun.enable",
+		kind: fieldBool,
+		value: func(c clash.Config) (string, bool) {
+			if c.Tun == nil {
+				return "", false
+			}
+			return strconv.FormatBool(c.Tun.Enable), true
+		},
+		apply: func(p *clash.ConfigPatch, v string) { b := v == "true"; p.Tun = &clash.TunPatch{Enable: &b} },
+	},
+	{
+		key:   "mixed-port",
+		kind:  fieldPort,
+		value: func(c clash.Config) (string, bool) { return strconv.Itoa(c.MixedPort), true },
+		apply: func(p *clash.ConfigPatch, v string) { n, _ := strconv.Atoi(v); p.MixedPort = &n },
+	},
+	{
+		key:   "socks-port",
+		kind:  fieldPort,
+		value: func(c clash.Config) (string, bool) { return strconv.Itoa(c.SocksPort), true },
+		apply: func(p *clash.ConfigPatch, v string) { n, _ := strconv.Atoi(v); p.SocksPort = &n },
+	},
+}
+
+// validate checks a value before it is queued or sent.
+func (f configField) validate(v string) error {
+	switch f.kind {
+	case fieldBool:
+		if v != "true" && v != "false" {
+			return errors.New("must be true or false")
+		}
+	case fieldEnum:
+		if !slices.Contains(f.options, v) {
+			return fmt.Errorf("must be one of %v", f.options)
+		}
+	case fieldPort:
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		if !clash.ValidPort(n) {
+			return errors.New("must be 0-65535 (0 turns it off)")
+		}
+	}
+	return nil
+}
+
+// configState is the state of the Config page.
+type configState struct {
+	current *clash.Config
+	err     error
+	cursor  int
+	pending map[string]string // field key to the new value
+	edit    textInput         // port being typed
+	invalid string            // why the typed port is rejected
+}
+
+type configLoadedMsg struct {
+	config *clash.Config
+	err    error
+}
+
+type configAppliedMsg struct {
+	changes int
+	err     error
+}
+
+func fetchConfigCmd(backend clash.Backend) tea.Cmd {
+	return func() tea.Msg {
+		config, err := backend.GetConfigContext(context.Background())
+		return configLoadedMsg{config: config, err: err}
+	}
+}
+
+func applyConfigCmd(backend clash.Backend, patch clash.ConfigPatch, changes int) tea.Cmd {
+	return func() tea.Msg {
+		err := backend.PatchConfigContext(context.Background(), patch)
+		return configAppliedMsg{changes: changes, err: err}
+	}
+}
+
+func (m Model) handleConfigLoaded(msg configLoadedMsg) (Model, tea.Cmd) {
+	if msg.err != nil {
+		if errors.Is(msg.err, context.Canceled) {
+			return m, nil
+		}
+		m.config.err = msg.err
+		return m, nil
+	}
+	m.config.err = nil
+	m.config.current = msg.config
+	m.setMode(msg.config.Mode)
+
+	// Changes the core already has are no longer pending
+	for _, f := range configFields {
+		if v, ok := f.value(*msg.config); !ok || m.config.pending[f.key] == v {
+			delete(m.config.pending, f.key)
+		}
+	}
+	return m, nil
+}
+
+func (m Model) handleConfigApplied(msg configAppliedMsg) (Model, tea.Cmd) {
+	if msg.err != nil {
+		return m, m.setNotice(fmt.Sprintf("Applying changes failed: %v", msg.err), true)
+	}
+	m.config.pending = nil
+	text := fmt.Sprintf("Applied %d changes", msg.changes)
+	if msg.changes == 1 {
+		text = "Applied 1 change"
+	}
+	return m, tea.Batch(m.setNotice(text, false), fetchConfigCmd(m.Backend))
+}
+
+// setPending queues a new value for a field, or drops it when it matches
+// the running config.
+func (m *Model) setPending(f configField, v string) {
+	if cur, _ := f.value(*m.config.current); v == cur {
+		delete(m.config.pending, f.key)
+		return
+	}
+	if m.config.pending == nil {
+		m.config.pending = make(map[string]string)
+	}
+	m.config.pending[f.key] = v
+}
+
+// fieldValue is the pending value of a field, or else its current one.
+func (m Model) fieldValue(f configField) (string, bool) {
+	if v, ok := m.config.pending[f.key]; ok {
+		return v, true
+	}
+	return f.value(*m.config.current)
+}
+
+// configInputChanged checks the port being typed and queues it once Enter
+// is pressed. An invalid port keeps the editor open.
+func (m *Model) configInputChanged() {
+	f := configFields[m.config.cursor]
+	v := m.config.edit.value
+	if v == "" {
+		m.config.invalid = ""
+		return
+	}
+	if err := f.validate(v); err != nil {
+		m.config.invalid = err.Error()
+		m.config.edit.active = true
+		return
+	}
+	m.config.invalid = ""
+	if !m.config.edit.active {
+		m.setPending(f, v)
+		m.config.edit.value = ""
+	}
+}
+
+// configPatch builds the patch for the pending changes, validating each.
+func (m Model) configPatch() (clash.ConfigPatch, error) {
+	var patch clash.ConfigPatch
+	for _, f := range configFields {
+		v, ok := m.config.pending[f.key]
+		if !ok {
+			continue
+		}
+		if err := f.validate(v); err != nil {
+			return patch, fmt.Errorf("%s %s", f.key, err)
+		}
+		f.apply(&patch, v)
+	}
+	return patch, nil
+}
+
+func (m Model) updateConfig(msg tea.KeyPressMsg) (Model, tea.Cmd) {
+	key := msg.Key()
+	if key.Text == "r" && key.Mod == 0 {
+		return m, fetchConfigCmd(m.Backend)
+	}
+	if m.config.current == nil {
+		return m, nil
+	}
+
+	f := configFields[m.config.cursor]
+	switch {
+	case key.Code == tea.KeyUp || (key.Text == "k" && key.Mod == 0):
+		m.config.cursor = max(m.config.cursor-1, 0)
+	case key.Code == tea.KeyDown || (key.Text == "j" && key.Mod == 0):
+		m.config.cursor = min(m.config.cursor+1, len(configFields)-1)
+	case key.Code == tea.KeyHome || (key.Text == "g" && key.Mod == 0):
+		m.config.cursor = 0
+	case key.Code == tea.KeyEnd || key.Text == "G":
+		m.config.cursor = len(configFields) - 1
+
+	case key.Code == tea.KeyEnter || key.Code == tea.KeySpace:
+		v, ok := m.fieldValue(f)
+		if !ok {
+			return m, m.setNotice(f.key+" is not reported by this core", true)
+		}
+		switch f.kind {
+		case fieldBool:
+			m.setPending(f, strconv.FormatBool(v != "true"))
+		case fieldEnum:
+			i := slices.Index(f.options, v)
+			m.setPending(f, f.options[(i+1)%len(f.options)])
+		case fieldPort:
+			m.config.edit = textInput{active: true, value: v}
+		}
+
+	case key.Text == "x" && key.Mod == 0:
+		delete(m.config.pending, f.key)
+	case key.Text == "X":
+		m.config.pending = nil
+
+	case key.Text == "s" && key.Mod == 0:
+		if len(m.config.pending) == 0 {
+			return m, nil
+		}
+		patch, err := m.configPatch()
+		if err != nil {
+			return m, m.setNotice(err.Error(), true)
+		}
+		n := len(m.config.pending)
+		prompt := fmt.Sprintf("Apply %d changes?", n)
+		if n == 1 {
+			prompt = "Apply 1 change?"
+		}
+		m.askConfirm(prompt, applyConfigCmd(m.Backend, patch, n))
+	}
+	return m, nil
+}
+
+func (m Model) viewConfig() string {
+	s := selectedGroupStyle.Render(" Runtime config ") + "\n"
+
+	switch {
+	case m.config.err != nil:
+		return s + fixedIndicatorStyle.Render("  "+m.config.err.Error()) + "\n"
+	case m.config.current == nil:
+		return s + helpStyle.Render("  Loading config...") + "\n"
+	}
+
+	for i, f := range configFields {
+		marker := "   "
+		if i == m.config.cursor {
+			marker = cursorStyle.Render(">  ")
+		}
+		line := fmt.Sprintf("%-12s ", f.key)
+		cur, ok := f.value(*m.config.current)
+		next, changed := m.config.pending[f.key]
+		switch {
+		case !ok:
+			line += helpStyle.Render("n/a")
+		case i == m.config.cursor && m.config.edit.active:
+			line += m.config.edit.view("")
+			if m.config.invalid != "" {
+				line += " " + fixedIndicatorStyle.Render("✖ "+m.config.invalid)
+			}
+		case changed:
+			line += helpStyle.Render(cur) + separatorStyle.Render(" → ") + hintStyle.Render(next)
+		default:
+			line += cur
+		}
+		s += m.fit(marker+line) + "\n"
+	}
+
+	if len(m.config.pending) == 0 {
+		return s
+	}
+	s += "\n" + headerStyle.Render("  Pending changes") + helpStyle.Render(" ([s] apply, [x] revert field, [X] discard all)") + "\n"
+	for _, f := range configFields {
+		next, ok := m.config.pending[f.key]
+		if !ok {
+			continue
+		}
+		cur, _ := f.value(*m.config.current)
+		s += m.fit(diffRemovedStyle.Render(fmt.Sprintf("  - %s: %s", f.key, cur))) + "\n"
+		s += m.fit(diffAddedStyle.Render(fmt.Sprintf("  + %s: %s", f.key, next))) + "\n"
+	}
+	return s
+}
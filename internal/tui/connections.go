@@ -0,0 +1,420 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/wallacegibbon/proxy-controller-tui/internal/clash"
+)
+
+const connectionsRefreshInterval = time.Second
+
+// connSort orders the connections list.
+type connSort int
+
+const (
+	sortByTraffic connSort = iota // most bytes first
+	sortByAge                     // oldest first
+	connSortCount
+)
+
+func (s connSort) String() string {
+	if s == sortByAge {
+		return "age"
+	}
+	return "traffic"
+}
+
+var (
+	connHostStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("231"))
+	connChainStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("86"))
+	connRuleStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("147"))
+	connDetailStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
+)
+
+// connectionsState is the state of the Connections page.
+type connectionsState struct {
+	all           []clash.Connection // as returned by the core
+	list          []clash.Connection // all, filtered and sorted
+	filter        textInput
+	uploadTotal   int64
+	downloadTotal int64
+	cursor        int
+	offset        int
+	sortBy        connSort
+	selectedID    string // keeps the cursor on the same connection across refreshes
+	loaded        bool
+	err           error
+	seq           int // generation of the refresh loop, stale loops stop
+}
+
+type connectionsMsg struct {
+	seq  int
+	resp *clash.ConnectionsResponse
+	err  error
+}
+
+type connectionsTickMsg struct {
+	seq int
+}
+
+// connectionsClosedMsg reports a close action; failed connections may have
+// closed on their own in the meantime.
+type connectionsClosedMsg struct {
+	group  string // set when closed after switching the group
+	closed int
+	total  int
+	err    error
+}
+
+func fetchConnectionsCmd(backend clash.Backend, seq int) tea.Cmd {
+	return func() tea.Msg {
+		resp, err := backend.GetConnectionsContext(context.Background())
+		return connectionsMsg{seq: seq, resp: resp, err: err}
+	}
+}
+
+// closeConnectionsCmd closes the given connections one by one.
+func closeConnectionsCmd(backend clash.Backend, ids []string) tea.Cmd {
+	return func() tea.Msg {
+		msg := connectionsClosedMsg{total: len(ids)}
+		for _, id := range ids {
+			if err := backend.CloseConnectionContext(context.Background(), id); err != nil {
+				if msg.err == nil {
+					msg.err = err
+				}
+				continue
+			}
+			msg.closed++
+		}
+		return msg
+	}
+}
+
+// closeAllConnectionsCmd closes every connection of the core, including
+// those opened after the last refresh. total is what the page showed.
+func closeAllConnectionsCmd(backend clash.Backend, total int) tea.Cmd {
+	return func() tea.Msg {
+		if err := backend.CloseAllConnectionsContext(context.Background()); err != nil {
+			return connectionsClosedMsg{total: total, err: err}
+		}
+		return connectionsClosedMsg{closed: total, total: total}
+	}
+}
+
+// closeGroupConnectionsCmd closes every connection whose chain goes through
+// group.
+func closeGroupConnectionsCmd(backend clash.Backend, group string) tea.Cmd {
+	return func() tea.Msg {
+		resp, err := backend.GetConnectionsContext(context.Background())
+		if err != nil {
+			return connectionsClosedMsg{group: group, err: err}
+		}
+		var ids []string
+		for _, c := range resp.Connections {
+			if slices.Contains(c.Chains, group) {
+				ids = append(ids, c.ID)
+			}
+		}
+		msg := closeConnectionsCmd(backend, ids)().(connectionsClosedMsg)
+		msg.group = group
+		return msg
+	}
+}
+
+func connectionsTickCmd(seq int) tea.Cmd {
+	return tea.Tick(connectionsRefreshInterval, func(time.Time) tea.Msg {
+		return connectionsTickMsg{seq: seq}
+	})
+}
+
+// startConnectionsRefresh starts a new refresh loop, retiring any old one.
+func (m Model) startConnectionsRefresh() (Model, tea.Cmd) {
+	m.conns.seq++
+	return m, fetchConnectionsCmd(m.Backend, m.conns.seq)
+}
+
+func (m Model) handleConnectionsMsg(msg connectionsMsg) (Model, tea.Cmd) {
+	if msg.seq != m.conns.seq || m.page != pageConnections {
+		return m, nil
+	}
+	if msg.err != nil {
+		if errors.Is(msg.err, context.Canceled) {
+			return m, nil
+		}
+		// Shown inline; the loop keeps polling until the core answers again
+		m.conns.err = msg.err
+		return m, connectionsTickCmd(msg.seq)
+	}
+
+	m.conns.err = nil
+	m.conns.loaded = true
+	m.conns.all = msg.resp.Connections
+	m.conns.uploadTotal = msg.resp.UploadTotal
+	m.conns.downloadTotal = msg.resp.DownloadTotal
+	m.applyConnections()
+	return m, connectionsTickCmd(msg.seq)
+}
+
+func (m Model) handleConnectionsClosed(msg connectionsClosedMsg) (Model, tea.Cmd) {
+	var notice tea.Cmd
+	suffix := ""
+	if msg.group != "" {
+		suffix = " through " + msg.group
+	}
+	switch {
+	case msg.err != nil && msg.total > 1:
+		notice = m.setNotice(fmt.Sprintf("Closed %d of %d connections%s: %v", msg.closed, msg.total, suffix, msg.err), true)
+	case msg.err != nil:
+		notice = m.setNotice(fmt.Sprintf("Closing connections%s failed: %v", suffix, msg.err), true)
+	case msg.closed == 1:
+		notice = m.setNotice("Closed 1 connection"+suffix, false)
+	default:
+		notice = m.setNotice(fmt.Sprintf("Closed %d connections%s", msg.closed, suffix), false)
+	}
+	if m.page != pageConnections {
+		return m, notice
+	}
+	next, cmd := m.startConnectionsRefresh()
+	return next, tea.Batch(notice, cmd)
+}
+
+func (m Model) handleConnectionsTick(msg connectionsTickMsg) (Model, tea.Cmd) {
+	if msg.seq != m.conns.seq || m.page != pageConnections {
+		return m, nil
+	}
+	return m, fetchConnectionsCmd(m.Backend, msg.seq)
+}
+
+// applyConnections rebuilds the visible list from the filter.
+func (m *Model) applyConnections() {
+	query := strings.ToLower(m.conns.filter.value)
+	list := make([]clash.Connection, 0, len(m.conns.all))
+	for _, c := range m.conns.all {
+		if query == "" || matchConnection(c, query) {
+			list = append(list, c)
+		}
+	}
+	m.conns.list = list
+	m.sortConnections()
+}
+
+// matchConnection reports whether a lower-case query appears in the host,
+// destination, chain, rule or process of c.
+func matchConnection(c clash.Connection, query string) bool {
+	md := c.Metadata
+	for _, field := range []string{md.Host, md.DestinationIP, md.Network, md.Process, c.Rule, c.RulePayload, strings.Join(c.Chains, " ")} {
+		if strings.Contains(strings.ToLower(field), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortConnections orders the list and puts the cursor back on the
+// connection it was on.
+func (m *Model) sortConnections() {
+	list := m.conns.list
+	switch m.conns.sortBy {
+	case sortByAge:
+		sort.SliceStable(list, func(i, j int) bool {
+			if !list[i].Start.Equal(list[j].Start) {
+				return list[i].Start.Before(list[j].Start)
+			}
+			return list[i].ID < list[j].ID
+		})
+	default:
+		sort.SliceStable(list, func(i, j int) bool {
+			ti, tj := list[i].Upload+list[i].Download, list[j].Upload+list[j].Download
+			if ti != tj {
+				return ti > tj
+			}
+			return list[i].ID < list[j].ID
+		})
+	}
+
+	for i, c := range list {
+		if c.ID == m.conns.selectedID {
+			m.conns.cursor = i
+			break
+		}
+	}
+	m.setConnectionsCursor(m.conns.cursor)
+}
+
+func (m *Model) setConnectionsCursor(cursor int) {
+	if cursor >= len(m.conns.list) {
+		cursor = len(m.conns.list) - 1
+	}
+	if cursor < 0 {
+		cursor = 0
+	}
+	m.conns.cursor = cursor
+	if cursor < len(m.conns.list) {
+		m.conns.selectedID = m.conns.list[cursor].ID
+	}
+
+	rows := m.connectionRows()
+	if m.conns.cursor < m.conns.offset {
+		m.conns.offset = m.conns.cursor
+	} else if m.conns.cursor >= m.conns.offset+rows {
+		m.conns.offset = m.conns.cursor - rows + 1
+	}
+	if maxOffset := len(m.conns.list) - rows; m.conns.offset > maxOffset {
+		m.conns.offset = maxOffset
+	}
+	if m.conns.offset < 0 {
+		m.conns.offset = 0
+	}
+}
+
+// connectionRows is the number of list rows between the summary (and the
+// filter line, when shown) and the detail line.
+func (m Model) connectionRows() int {
+	n := m.Height - len(m.headerLines()) - 2
+	if m.conns.filterShown() {
+		n--
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+func (m Model) updateConnections(msg tea.KeyPressMsg) (Model, tea.Cmd) {
+	switch key := msg.Key(); {
+	case key.Code == tea.KeyUp || (key.Text == "k" && key.Mod == 0):
+		m.setConnectionsCursor(m.conns.cursor - 1)
+	case key.Code == tea.KeyDown || (key.Text == "j" && key.Mod == 0):
+		m.setConnectionsCursor(m.conns.cursor + 1)
+	case key.Code == tea.KeyPgUp:
+		m.setConnectionsCursor(m.conns.cursor - m.connectionRows())
+	case key.Code == tea.KeyPgDown:
+		m.setConnectionsCursor(m.conns.cursor + m.connectionRows())
+	case key.Code == tea.KeyHome || (key.Text == "g" && key.Mod == 0):
+		m.setConnectionsCursor(0)
+	case key.Code == tea.KeyEnd || key.Text == "G":
+		m.setConnectionsCursor(len(m.conns.list) - 1)
+	case key.Text == "s" && key.Mod == 0:
+		m.conns.sortBy = (m.conns.sortBy + 1) % connSortCount
+		m.sortConnections()
+	case key.Text == "r" && key.Mod == 0:
+		return m.startConnectionsRefresh()
+	case key.Text == "/":
+		m.conns.filter.active = true
+	case key.Text == "x" && key.Mod == 0:
+		if m.conns.cursor < len(m.conns.list) {
+			return m, closeConnectionsCmd(m.Backend, []string{m.conns.list[m.conns.cursor].ID})
+		}
+	case key.Text == "X":
+		if m.conns.filter.value == "" {
+			return m, m.setNotice("Set a filter with / first, or press C to close everything", true)
+		}
+		if len(m.conns.list) > 0 {
+			ids := make([]string, len(m.conns.list))
+			for i, c := range m.conns.list {
+				ids[i] = c.ID
+			}
+			m.askConfirm(fmt.Sprintf("Close %d connections matching %q?", len(ids), m.conns.filter.value),
+				closeConnectionsCmd(m.Backend, ids))
+		}
+	case key.Text == "C":
+		if len(m.conns.all) > 0 {
+			m.askConfirm(fmt.Sprintf("Close all %d connections?", len(m.conns.all)),
+				closeAllConnectionsCmd(m.Backend, len(m.conns.all)))
+		}
+	}
+	return m, nil
+}
+
+func (s connectionsState) filterShown() bool {
+	return s.filter.active || s.filter.value != ""
+}
+
+func (m Model) viewConnections() string {
+	var s string
+
+	count := fmt.Sprintf("%d connections", len(m.conns.list))
+	if m.conns.filter.value != "" {
+		count = fmt.Sprintf("%d/%d connections", len(m.conns.list), len(m.conns.all))
+	}
+	summary := fmt.Sprintf(" %s · sort: %s · ↑ %s ↓ %s ",
+		count, m.conns.sortBy, formatBytes(m.conns.uploadTotal), formatBytes(m.conns.downloadTotal))
+	s += selectedGroupStyle.Render(summary) + "\n"
+	if m.conns.filterShown() {
+		s += m.fit(m.conns.filter.view("/")) + "\n"
+	}
+
+	switch {
+	case m.conns.err != nil:
+		return s + fixedIndicatorStyle.Render("  "+m.conns.err.Error()) + "\n"
+	case !m.conns.loaded:
+		return s + helpStyle.Render("  Loading connections...") + "\n"
+	case len(m.conns.list) == 0 && len(m.conns.all) > 0:
+		return s + helpStyle.Render("  No connections match the filter") + "\n"
+	case len(m.conns.list) == 0:
+		return s + helpStyle.Render("  No active connections") + "\n"
+	}
+
+	now := time.Now()
+	end := m.conns.offset + m.connectionRows()
+	if end > len(m.conns.list) {
+		end = len(m.conns.list)
+	}
+	for i := m.conns.offset; i < end; i++ {
+		marker := "   "
+		if i == m.conns.cursor {
+			marker = cursorStyle.Render(">  ")
+		}
+		s += m.fit(marker+connectionLine(m.conns.list[i], now)) + "\n"
+	}
+
+	if m.conns.cursor < len(m.conns.list) {
+		s += m.fit(connDetailStyle.Render(connectionDetail(m.conns.list[m.conns.cursor]))) + "\n"
+	}
+	return s
+}
+
+func connectionLine(c clash.Connection, now time.Time) string {
+	host := c.Metadata.Host
+	if host == "" {
+		host = c.Metadata.DestinationIP
+	}
+	rule := c.Rule
+	if c.RulePayload != "" {
+		rule += "(" + c.RulePayload + ")"
+	}
+	return connHostStyle.Render(host+":"+c.Metadata.DestinationPort) + " " +
+		normalStyle.Render(c.Metadata.Network) + " " +
+		connChainStyle.Render(chainString(c.Chains)) + " " +
+		connRuleStyle.Render(rule) + " " +
+		normalStyle.Render(fmt.Sprintf("↑ %s ↓ %s %s", formatBytes(c.Upload), formatBytes(c.Download), formatAge(now.Sub(c.Start))))
+}
+
+// chainString shows a chain from the matched group to the outermost proxy.
+func chainString(chains []string) string {
+	parts := make([]string, len(chains))
+	for i, c := range chains {
+		parts[len(chains)-1-i] = c
+	}
+	return strings.Join(parts, " → ")
+}
+
+func connectionDetail(c clash.Connection) string {
+	md := c.Metadata
+	detail := fmt.Sprintf("  %s:%s → %s:%s · %s", md.SourceIP, md.SourcePort, md.DestinationIP, md.DestinationPort, md.Type)
+	if md.Process != "" {
+		detail += " · " + md.Process
+	}
+	if !c.Start.IsZero() {
+		detail += " · started " + c.Start.Local().Format("15:04:05")
+	}
+	return detail
+}
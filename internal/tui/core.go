@@ -0,0 +1,155 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/wallacegibbon/proxy-controller-tui/internal/clash"
+)
+
+const (
+	coreWaitTimeout  = 30 * time.Second       // give up waiting for the core after this
+	corePollInterval = 500 * time.Millisecond // between GetProxies attempts
+	restartGrace     = time.Second            // the core answers /restart before going down
+)
+
+// coreActionMsg reports the answer to a config reload or restart request.
+type coreActionMsg struct {
+	action string // "reload" or "restart"
+	err    error
+}
+
+// coreBackMsg ends the wait for the core after a reload or restart.
+type coreBackMsg struct {
+	loaded proxiesLoadedMsg
+	err    error
+}
+
+func reloadConfigCmd(backend clash.Backend) tea.Cmd {
+	return func() tea.Msg {
+		err := backend.ReloadConfigContext(context.Background(), clash.ReloadConfigRequest{})
+		return coreActionMsg{action: "reload", err: err}
+	}
+}
+
+func restartCoreCmd(backend clash.Backend) tea.Cmd {
+	return func() tea.Msg {
+		err := backend.RestartContext(context.Background())
+		return coreActionMsg{action: "restart", err: err}
+	}
+}
+
+// waitForCoreCmd polls GetProxies until the core answers again or
+// coreWaitTimeout passes.
+func waitForCoreCmd(backend clash.Backend, delay time.Duration) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), coreWaitTimeout)
+		defer cancel()
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return coreBackMsg{err: ctx.Err()}
+		}
+		for {
+			msg := loadProxies(ctx, backend)
+			if loaded, ok := msg.(proxiesLoadedMsg); ok {
+				return coreBackMsg{loaded: loaded}
+			}
+			select {
+			case <-time.After(corePollInterval):
+			case <-ctx.Done():
+				return coreBackMsg{err: msg.(errMsg)}
+			}
+		}
+	}
+}
+
+// confirmReloadConfig asks before making the core read its config file
+// again.
+func (m Model) confirmReloadConfig() (Model, tea.Cmd) {
+	if m.waitingCore != "" {
+		return m, nil
+	}
+	m.askConfirm("Reload the core's config file? Runtime changes are lost", reloadConfigCmd(m.Backend))
+	return m, nil
+}
+
+// confirmRestartCore asks before restarting the core.
+func (m Model) confirmRestartCore() (Model, tea.Cmd) {
+	if m.waitingCore != "" {
+		return m, nil
+	}
+	if !m.caps().Restart {
+		return m, m.unsupported("Restarting the core")
+	}
+	m.askConfirm("Restart the core? Connections are dropped", restartCoreCmd(m.Backend))
+	return m, nil
+}
+
+// handleCoreAction starts waiting for the core once it accepted the
+// request. The group on screen is remembered by name, as the list may
+// change.
+func (m Model) handleCoreAction(msg coreActionMsg) (Model, tea.Cmd) {
+	if msg.err != nil {
+		text := fmt.Sprintf("Reloading the config failed: %v", msg.err)
+		if msg.action == "restart" {
+			text = fmt.Sprintf("Restart failed: %v", msg.err)
+		}
+		return m, m.setNotice(text, true)
+	}
+
+	m.waitingCore = msg.action
+	m.restoreGroup = ""
+	if m.CurrentIdx < len(m.Groups) {
+		m.restoreGroup = m.Groups[m.CurrentIdx]
+	}
+	m.relayout()
+	delay := time.Duration(0)
+	if msg.action == "restart" {
+		delay = restartGrace
+	}
+	return m, waitForCoreCmd(m.Backend, delay)
+}
+
+// handleCoreBack shows the proxies of the core that came back, on the same
+// group and, through lastCursorProxy, the same proxy as before.
+func (m Model) handleCoreBack(msg coreBackMsg) (Model, tea.Cmd) {
+	action := m.waitingCore
+	m.waitingCore = ""
+	if msg.err != nil {
+		m.relayout()
+		return m.handleError(msg.err)
+	}
+
+	if i := slices.Index(msg.loaded.groups, m.restoreGroup); i >= 0 {
+		m.CurrentIdx = i
+	}
+	newModel, cmd := m.Update(msg.loaded)
+	m = newModel.(Model)
+
+	text := "Config reloaded"
+	if action == "restart" {
+		text = "Core restarted"
+	}
+	cmds := []tea.Cmd{cmd, m.setNotice(text, false), versionCmd(m.Backend), configCmd(m.Backend)}
+	if m.page == pageConfig {
+		cmds = append(cmds, fetchConfigCmd(m.Backend))
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// waitingLine is shown in the header while waiting for the core.
+func (m Model) waitingLine() string {
+	if m.waitingCore == "" {
+		return ""
+	}
+	what := "the config reload"
+	if m.waitingCore == "restart" {
+		what = "the restart"
+	}
+	return reconnectingStyle.Render("⟳ Waiting for the core after " + what + "...")
+}
@@ -0,0 +1,54 @@
+package tui
+
+import (
+	"fmt"
+	"time"
+)
+
+// formatBytes renders a byte count with a binary unit, e.g. "1.5 MB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// formatAge renders a duration compactly, e.g. "42s", "5m", "3h".
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline draws values as block characters scaled to their maximum.
+func sparkline(values []int64) string {
+	var max int64
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+	runes := make([]rune, len(values))
+	for i, v := range values {
+		level := 0
+		if max > 0 && v > 0 {
+			level = int(v * int64(len(sparkBlocks)-1) / max)
+		}
+		runes[i] = sparkBlocks[level]
+	}
+	return string(runes)
+}
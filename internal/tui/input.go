@@ -0,0 +1,72 @@
+package tui
+
+import (
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+)
+
+var inputStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("231"))
+
+// textInput is a minimal single-line editor used for filters, searches and
+// values.
+type textInput struct {
+	active bool
+	value  string
+}
+
+// update applies a key while the input is focused. Enter keeps the value,
+// Esc clears it; both give the focus back.
+func (t *textInput) update(key tea.Key) {
+	switch {
+	case key.Code == tea.KeyEnter:
+		t.active = false
+	case key.Code == tea.KeyEscape:
+		t.active = false
+		t.value = ""
+	case key.Code == tea.KeyBackspace:
+		if r := []rune(t.value); len(r) > 0 {
+			t.value = string(r[:len(r)-1])
+		}
+	case key.Text != "" && key.Mod&^tea.ModShift == 0:
+		t.value += key.Text
+	}
+}
+
+// view renders the input after a prompt such as "/", with a cursor while
+// focused.
+func (t textInput) view(prompt string) string {
+	s := prompt + t.value
+	if t.active {
+		s += "█"
+	}
+	return inputStyle.Render(s)
+}
+
+// focusedInput returns the input that currently receives typed keys.
+func (m *Model) focusedInput() *textInput {
+	switch {
+	case m.page == pageConnections && m.conns.filter.active:
+		return &m.conns.filter
+	case m.page == pageLogs && m.logs.search.active:
+		return &m.logs.search
+	case m.page == pageRules && m.rules.filter.active:
+		return &m.rules.filter
+	case m.page == pageConfig && m.config.edit.active:
+		return &m.config.edit
+	}
+	return nil
+}
+
+// inputChanged reapplies whatever depends on the focused input's value.
+func (m *Model) inputChanged() {
+	switch m.page {
+	case pageConnections:
+		m.applyConnections()
+	case pageLogs:
+		m.searchLogs()
+	case pageRules:
+		m.applyRules()
+	case pageConfig:
+		m.configInputChanged()
+	}
+}
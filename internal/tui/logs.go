@@ -0,0 +1,473 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/wallacegibbon/proxy-controller-tui/internal/clash"
+)
+
+const logBufferSize = 1000 // lines kept on the Logs page
+
+// logLevels are the levels the Logs page cycles through, most verbose first.
+var logLevels = []string{clash.LogDebug, clash.LogInfo, clash.LogWarning, clash.LogError}
+
+var (
+	logTimeStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	logDebugStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
+	logInfoStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("75"))
+	logWarningStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+	logErrorStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	logTextStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("252"))
+	logHighlightStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("16")).Background(lipgloss.Color("226"))
+)
+
+// logRank orders levels by severity; unknown levels count as info.
+func logRank(level string) int {
+	if rank := clash.LogLevelRank(level); rank >= 0 {
+		return rank
+	}
+	return clash.LogLevelRank(clash.LogInfo)
+}
+
+func logLevelStyle(level string) lipgloss.Style {
+	switch level {
+	case clash.LogDebug:
+		return logDebugStyle
+	case clash.LogWarning:
+		return logWarningStyle
+	case clash.LogError:
+		return logErrorStyle
+	}
+	return logInfoStyle
+}
+
+// logLine is a received log entry. The core does not timestamp them, so
+// the time is when it arrived.
+type logLine struct {
+	at    time.Time
+	level string
+	text  string
+}
+
+// logRing keeps the newest logBufferSize lines.
+type logRing struct {
+	lines []logLine
+	start int // index of the oldest line once full
+}
+
+func (r *logRing) push(l logLine) {
+	if len(r.lines) < logBufferSize {
+		r.lines = append(r.lines, l)
+		return
+	}
+	r.lines[r.start] = l
+	r.start = (r.start + 1) % len(r.lines)
+}
+
+// all returns the lines oldest first.
+func (r logRing) all() []logLine {
+	out := make([]logLine, 0, len(r.lines))
+	out = append(out, r.lines[r.start:]...)
+	return append(out, r.lines[:r.start]...)
+}
+
+// logsState is the state of the Logs page.
+type logsState struct {
+	stream  *clash.Stream[clash.LogEntry]
+	level   string // requested from the core
+	seq     int    // generation of the stream, lines of older ones are dropped
+	started bool
+	buf     logRing
+	pending []logLine // received while paused
+	paused  bool
+	search  textInput
+	cursor  int // index into visibleLogs()
+	offset  int
+	follow  bool // keep the cursor on the newest line
+	err     error
+}
+
+type logsOpenedMsg struct {
+	seq    int
+	stream *clash.Stream[clash.LogEntry]
+	err    error
+}
+
+type logMsg struct {
+	seq   int
+	entry clash.LogEntry
+	err   error
+}
+
+type logsRetryMsg struct {
+	seq int
+}
+
+type logsSavedMsg struct {
+	path string
+	err  error
+}
+
+func openLogsCmd(backend clash.Backend, level string, seq int) tea.Cmd {
+	return func() tea.Msg {
+		stream, err := backend.StreamLogsContext(context.Background(), level)
+		return logsOpenedMsg{seq: seq, stream: stream, err: err}
+	}
+}
+
+func nextLogCmd(stream *clash.Stream[clash.LogEntry], seq int) tea.Cmd {
+	return func() tea.Msg {
+		entry, err := stream.Next()
+		return logMsg{seq: seq, entry: entry, err: err}
+	}
+}
+
+func logsRetryCmd(seq int) tea.Cmd {
+	return tea.Tick(streamRetryDelay, func(time.Time) tea.Msg {
+		return logsRetryMsg{seq: seq}
+	})
+}
+
+// saveLogsCmd writes lines to a timestamped file in the working directory.
+func saveLogsCmd(lines []logLine) tea.Cmd {
+	return func() tea.Msg {
+		name := "proxy-controller-tui-" + time.Now().Format("20060102-150405") + ".log"
+		path, err := filepath.Abs(name)
+		if err != nil {
+			return logsSavedMsg{err: err}
+		}
+		var b strings.Builder
+		for _, l := range lines {
+			fmt.Fprintf(&b, "%s [%s] %s\n", l.at.Format(time.DateTime), strings.ToUpper(l.level), l.text)
+		}
+		return logsSavedMsg{path: path, err: os.WriteFile(path, []byte(b.String()), 0o644)}
+	}
+}
+
+// startLogs (re)opens the stream at the selected level.
+func (m Model) startLogs() (Model, tea.Cmd) {
+	if m.logs.stream != nil {
+		m.logs.stream.Close()
+		m.logs.stream = nil
+	}
+	if m.logs.level == "" {
+		m.logs.level = clash.LogInfo
+	}
+	if !m.logs.started {
+		m.logs.started = true
+		m.logs.follow = true
+	}
+	m.logs.seq++
+	return m, openLogsCmd(m.Backend, m.logs.level, m.logs.seq)
+}
+
+func (m Model) handleLogsOpened(msg logsOpenedMsg) (Model, tea.Cmd) {
+	if msg.seq != m.logs.seq {
+		if msg.stream != nil {
+			msg.stream.Close()
+		}
+		return m, nil
+	}
+	if msg.err != nil {
+		m.logs.err = msg.err
+		if clash.StatusCode(msg.err) == http.StatusNotFound {
+			return m, nil
+		}
+		return m, logsRetryCmd(msg.seq)
+	}
+	m.logs.err = nil
+	m.logs.stream = msg.stream
+	return m, nextLogCmd(msg.stream, msg.seq)
+}
+
+func (m Model) handleLog(msg logMsg) (Model, tea.Cmd) {
+	if msg.seq != m.logs.seq {
+		return m, nil
+	}
+	if msg.err != nil {
+		m.logs.stream.Close()
+		m.logs.stream = nil
+		if errors.Is(msg.err, context.Canceled) {
+			return m, nil
+		}
+		// Shown inline; the stream is reopened when the core is back
+		m.logs.err = msg.err
+		return m, logsRetryCmd(msg.seq)
+	}
+
+	line := logLine{at: time.Now(), level: msg.entry.Type, text: msg.entry.Payload}
+	if m.logs.paused {
+		m.logs.pending = append(m.logs.pending, line)
+		if n := len(m.logs.pending); n > logBufferSize {
+			m.logs.pending = m.logs.pending[n-logBufferSize:]
+		}
+	} else {
+		m.logs.buf.push(line)
+		m.followLogs()
+	}
+	return m, nextLogCmd(m.logs.stream, msg.seq)
+}
+
+func (m Model) handleLogsRetry(msg logsRetryMsg) (Model, tea.Cmd) {
+	if msg.seq != m.logs.seq {
+		return m, nil
+	}
+	return m, openLogsCmd(m.Backend, m.logs.level, msg.seq)
+}
+
+func (m Model) handleLogsSaved(msg logsSavedMsg) (Model, tea.Cmd) {
+	if msg.err != nil {
+		return m, m.setNotice(fmt.Sprintf("Saving logs failed: %v", msg.err), true)
+	}
+	return m, m.setNotice("Saved logs to "+msg.path, false)
+}
+
+// visibleLogs are the buffered lines at the selected level or above. Lines
+// buffered at a more verbose level are hidden, not dropped.
+func (m Model) visibleLogs() []logLine {
+	min := logRank(m.logs.level)
+	var out []logLine
+	for _, l := range m.logs.buf.all() {
+		if logRank(l.level) >= min {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// followLogs keeps the cursor on the newest line while following, and in
+// range otherwise.
+func (m *Model) followLogs() {
+	if m.logs.follow {
+		m.setLogsCursor(len(m.visibleLogs()) - 1)
+		return
+	}
+	m.setLogsCursor(m.logs.cursor)
+}
+
+func (m *Model) setLogsCursor(cursor int) {
+	n := len(m.visibleLogs())
+	if cursor >= n {
+		cursor = n - 1
+	}
+	if cursor < 0 {
+		cursor = 0
+	}
+	m.logs.cursor = cursor
+
+	rows := m.logRows()
+	if cursor < m.logs.offset {
+		m.logs.offset = cursor
+	} else if cursor >= m.logs.offset+rows {
+		m.logs.offset = cursor - rows + 1
+	}
+	if maxOffset := n - rows; m.logs.offset > maxOffset {
+		m.logs.offset = maxOffset
+	}
+	if m.logs.offset < 0 {
+		m.logs.offset = 0
+	}
+}
+
+// moveLogsCursor moves the cursor; following resumes on the newest line.
+func (m *Model) moveLogsCursor(cursor int) {
+	m.setLogsCursor(cursor)
+	m.logs.follow = !m.logs.paused && m.logs.cursor >= len(m.visibleLogs())-1
+}
+
+func (s logsState) searchShown() bool {
+	return s.search.active || s.search.value != ""
+}
+
+// logRows is the number of lines below the summary and search lines.
+func (m Model) logRows() int {
+	n := m.Height - len(m.headerLines()) - 1
+	if m.logs.searchShown() {
+		n--
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// findLog returns the next visible line from start, in direction dir and
+// wrapping around, that contains the search text; -1 if none does.
+func (m Model) findLog(start, dir int) int {
+	query := m.logs.search.value
+	lines := m.visibleLogs()
+	if query == "" || len(lines) == 0 {
+		return -1
+	}
+	for i := range lines {
+		idx := ((start+dir*i)%len(lines) + len(lines)) % len(lines)
+		if containsFold(lines[idx].text, query) {
+			return idx
+		}
+	}
+	return -1
+}
+
+// searchLogs jumps to a match as the search text is typed.
+func (m *Model) searchLogs() {
+	if idx := m.findLog(m.logs.cursor, 1); idx >= 0 {
+		m.moveLogsCursor(idx)
+	}
+}
+
+func (m Model) updateLogs(msg tea.KeyPressMsg) (Model, tea.Cmd) {
+	switch key := msg.Key(); {
+	case key.Code == tea.KeyUp || (key.Text == "k" && key.Mod == 0):
+		m.moveLogsCursor(m.logs.cursor - 1)
+	case key.Code == tea.KeyDown || (key.Text == "j" && key.Mod == 0):
+		m.moveLogsCursor(m.logs.cursor + 1)
+	case key.Code == tea.KeyPgUp:
+		m.moveLogsCursor(m.logs.cursor - m.logRows())
+	case key.Code == tea.KeyPgDown:
+		m.moveLogsCursor(m.logs.cursor + m.logRows())
+	case key.Code == tea.KeyHome || (key.Text == "g" && key.Mod == 0):
+		m.moveLogsCursor(0)
+	case key.Code == tea.KeyEnd || key.Text == "G":
+		m.moveLogsCursor(len(m.visibleLogs()) - 1)
+
+	case key.Code == tea.KeySpace || (key.Text == "p" && key.Mod == 0):
+		m.logs.paused = !m.logs.paused
+		if !m.logs.paused {
+			for _, l := range m.logs.pending {
+				m.logs.buf.push(l)
+			}
+			m.logs.pending = nil
+			m.logs.follow = true
+			m.followLogs()
+		} else {
+			m.logs.follow = false
+		}
+
+	case key.Text == "L":
+		m.logs.level = logLevels[(logRank(m.logs.level)+1)%len(logLevels)]
+		m.followLogs()
+		return m.startLogs()
+
+	case key.Text == "/":
+		m.logs.search.active = true
+	case key.Text == "n" && key.Mod == 0:
+		if idx := m.findLog(m.logs.cursor+1, 1); idx >= 0 {
+			m.moveLogsCursor(idx)
+		}
+	case key.Text == "N":
+		if idx := m.findLog(m.logs.cursor-1, -1); idx >= 0 {
+			m.moveLogsCursor(idx)
+		}
+
+	case key.Text == "w" && key.Mod == 0:
+		lines := m.logs.buf.all()
+		if len(lines) == 0 {
+			return m, m.setNotice("No logs to save", true)
+		}
+		return m, saveLogsCmd(lines)
+	}
+	return m, nil
+}
+
+func (m Model) viewLogs() string {
+	lines := m.visibleLogs()
+	state := "following"
+	switch {
+	case m.logs.paused:
+		state = fmt.Sprintf("paused (%d new)", len(m.logs.pending))
+	case !m.logs.follow:
+		state = "scrolled"
+	}
+	level := m.logs.level
+	if level == "" {
+		level = clash.LogInfo
+	}
+	summary := fmt.Sprintf(" %d lines · level: %s · %s ", len(lines), level, state)
+	s := selectedGroupStyle.Render(summary) + "\n"
+	if m.logs.searchShown() {
+		s += m.fit(m.logs.search.view("/")) + "\n"
+	}
+
+	switch {
+	case m.logs.err != nil && len(lines) == 0:
+		return s + fixedIndicatorStyle.Render("  "+m.logs.err.Error()) + "\n"
+	case len(lines) == 0:
+		return s + helpStyle.Render("  Waiting for logs...") + "\n"
+	}
+
+	end := m.logs.offset + m.logRows()
+	if end > len(lines) {
+		end = len(lines)
+	}
+	for i := m.logs.offset; i < end; i++ {
+		marker := "  "
+		if i == m.logs.cursor {
+			marker = cursorStyle.Render("> ")
+		}
+		s += m.fit(marker+m.logLineView(lines[i])) + "\n"
+	}
+	return s
+}
+
+func (m Model) logLineView(l logLine) string {
+	return logTimeStyle.Render(l.at.Format("15:04:05")) + " " +
+		logLevelStyle(l.level).Render(fmt.Sprintf("%-7s", l.level)) + " " +
+		highlight(l.text, m.logs.search.value)
+}
+
+// highlight marks every case-insensitive occurrence of query in text. It
+// folds like containsFold, so search and highlighting agree.
+func highlight(text, query string) string {
+	if query == "" {
+		return logTextStyle.Render(text)
+	}
+	lower, offsets := lowerWithOffsets(text)
+	query = strings.ToLower(query)
+
+	var b strings.Builder
+	last := 0
+	for i := 0; ; {
+		j := strings.Index(lower[i:], query)
+		if j < 0 {
+			break
+		}
+		start, end := offsets[i+j], offsets[i+j+len(query)]
+		b.WriteString(logTextStyle.Render(text[last:start]))
+		b.WriteString(logHighlightStyle.Render(text[start:end]))
+		last = end
+		i += j + len(query)
+	}
+	b.WriteString(logTextStyle.Render(text[last:]))
+	return b.String()
+}
+
+// lowerWithOffsets returns strings.ToLower(text) and, for each byte of it
+// that starts a rune and for its end, the matching offset in text. Lowering
+// can change a rune's length, e.g. "Ⱥ" (2 bytes) becomes "ⱥ" (3 bytes).
+func lowerWithOffsets(text string) (string, []int) {
+	var b strings.Builder
+	offsets := make([]int, 0, len(text)+1)
+	for i, r := range text {
+		n := b.Len()
+		b.WriteRune(unicode.ToLower(r))
+		for range b.Len() - n {
+			offsets = append(offsets, i)
+		}
+	}
+	offsets = append(offsets, len(text))
+	return b.String(), offsets
+}
+
+func containsFold(text, query string) bool {
+	return strings.Contains(strings.ToLower(text), strings.ToLower(query))
+}
@@ -0,0 +1,122 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/wallacegibbon/proxy-controller-tui/internal/clash"
+)
+
+const memoryHistoryLen = 12 // samples kept for the trend graph
+
+var (
+	memoryStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("147"))
+	memoryHighStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Bold(true)
+)
+
+// memoryState follows the /memory stream.
+type memoryState struct {
+	stream      *clash.Stream[clash.MemoryUsage]
+	history     []int64 // in-use bytes, oldest first
+	unsupported bool
+}
+
+type memoryOpenedMsg struct {
+	stream *clash.Stream[clash.MemoryUsage]
+	err    error
+}
+
+type memoryMsg struct {
+	sample clash.MemoryUsage
+	err    error
+}
+
+type memoryRetryMsg struct{}
+
+func openMemoryCmd(backend clash.Backend) tea.Cmd {
+	return func() tea.Msg {
+		stream, err := backend.StreamMemoryContext(context.Background())
+		return memoryOpenedMsg{stream: stream, err: err}
+	}
+}
+
+func nextMemoryCmd(stream *clash.Stream[clash.MemoryUsage]) tea.Cmd {
+	return func() tea.Msg {
+		sample, err := stream.Next()
+		return memoryMsg{sample: sample, err: err}
+	}
+}
+
+func memoryRetryCmd() tea.Cmd {
+	return tea.Tick(streamRetryDelay, func(time.Time) tea.Msg {
+		return memoryRetryMsg{}
+	})
+}
+
+func (m Model) handleMemoryOpened(msg memoryOpenedMsg) (Model, tea.Cmd) {
+	if msg.err != nil {
+		// Plain Clash has no /memory
+		if clash.StatusCode(msg.err) == http.StatusNotFound {
+			m.memory.unsupported = true
+			return m, nil
+		}
+		return m, memoryRetryCmd()
+	}
+	m.memory.stream = msg.stream
+	return m, nextMemoryCmd(msg.stream)
+}
+
+func (m Model) handleMemory(msg memoryMsg) (Model, tea.Cmd) {
+	if msg.err != nil {
+		m.memory.stream.Close()
+		m.memory.stream = nil
+		if errors.Is(msg.err, context.Canceled) {
+			return m, nil
+		}
+		return m, memoryRetryCmd()
+	}
+
+	// Mihomo's first sample is always 0
+	if msg.sample.InUse > 0 {
+		m.memory.history = append(m.memory.history, msg.sample.InUse)
+		if n := len(m.memory.history); n > memoryHistoryLen {
+			m.memory.history = append([]int64(nil), m.memory.history[n-memoryHistoryLen:]...)
+		}
+	}
+	return m, nextMemoryCmd(m.memory.stream)
+}
+
+// memoryIndicator shows the core's memory use with its trend, in red above
+// the configured threshold.
+func (m Model) memoryIndicator() string {
+	n := len(m.memory.history)
+	if n == 0 {
+		return ""
+	}
+	cur := m.memory.history[n-1]
+	s := "mem " + formatBytes(cur) + " " + sparkline(m.memory.history) + memoryTrend(m.memory.history)
+	if m.Options.MemoryThreshold > 0 && cur > m.Options.MemoryThreshold {
+		return memoryHighStyle.Render(s)
+	}
+	return memoryStyle.Render(s)
+}
+
+// memoryTrend compares the newest sample with the oldest one kept; changes
+// under 1% count as flat.
+func memoryTrend(history []int64) string {
+	if len(history) < 2 {
+		return ""
+	}
+	first, last := history[0], history[len(history)-1]
+	switch delta := last - first; {
+	case delta*100 > first:
+		return " ↑"
+	case -delta*100 > first:
+		return " ↓"
+	}
+	return " →"
+}
@@ -0,0 +1,100 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/wallacegibbon/proxy-controller-tui/internal/clash"
+)
+
+// globalGroup is the selector the core uses in global mode.
+const globalGroup = "GLOBAL"
+
+var (
+	modeRuleStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("147"))
+	modeOtherStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Bold(true)
+)
+
+type configMsg struct {
+	config *clash.Config
+}
+
+type modeChangedMsg struct {
+	mode string
+	err  error
+}
+
+// configCmd reads the running configuration for the mode indicator.
+// Failures are dropped like versionCmd's: the indicator stays hidden.
+func configCmd(backend clash.Backend) tea.Cmd {
+	return func() tea.Msg {
+		config, err := backend.GetConfigContext(context.Background())
+		if err != nil {
+			return nil
+		}
+		return configMsg{config: config}
+	}
+}
+
+func setModeCmd(backend clash.Backend, mode string) tea.Cmd {
+	return func() tea.Msg {
+		err := backend.PatchConfigContext(context.Background(), clash.ConfigPatch{Mode: &mode})
+		return modeChangedMsg{mode: mode, err: err}
+	}
+}
+
+func (m Model) handleConfig(msg configMsg) (Model, tea.Cmd) {
+	m.setMode(msg.config.Mode)
+	return m, nil
+}
+
+func (m Model) handleModeChanged(msg modeChangedMsg) (Model, tea.Cmd) {
+	if msg.err != nil {
+		return m, m.setNotice(fmt.Sprintf("Switching to %s mode failed: %v", msg.mode, msg.err), true)
+	}
+	m.setMode(msg.mode)
+	return m, m.setNotice("Mode: "+msg.mode, false)
+}
+
+// setMode records the core's mode. Entering global mode focuses the GLOBAL
+// group, now or once the proxies are loaded.
+func (m *Model) setMode(mode string) {
+	mode = strings.ToLower(mode) // Clash Premium says "Rule"
+	if mode == clash.ModeGlobal && m.mode != clash.ModeGlobal {
+		m.focusGlobal = true
+	}
+	m.mode = mode
+	m.applyGlobalFocus()
+}
+
+func (m *Model) applyGlobalFocus() {
+	if !m.focusGlobal || len(m.Groups) == 0 {
+		return
+	}
+	m.focusGlobal = false
+	if i := slices.Index(m.Groups, globalGroup); i >= 0 {
+		m.selectGroup(i)
+	}
+}
+
+// nextMode is the mode after the current one in clash.Modes.
+func (m Model) nextMode() string {
+	i := slices.Index(clash.Modes, m.mode)
+	return clash.Modes[(i+1)%len(clash.Modes)]
+}
+
+// modeIndicator renders the mode for the tabs line, highlighted unless it
+// is rule mode. It is empty until /configs answered.
+func (m Model) modeIndicator() string {
+	switch m.mode {
+	case "":
+		return ""
+	case clash.ModeRule:
+		return modeRuleStyle.Render("mode " + m.mode)
+	}
+	return modeOtherStyle.Render("mode " + m.mode)
+}
@@ -1,6 +1,9 @@
 package tui
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"sort"
 	"time"
 
@@ -11,13 +14,43 @@ import (
 
 type errMsg error
 
+// reloadMsg asks Update to start a fresh proxies load.
+type reloadMsg struct{}
+
 type resetFixedMsg struct {
 	groupName string
 	err       error
 }
 
+type selectProxyMsg struct {
+	groupName string
+	proxyName string
+	err       error
+}
+
+type groupDelayMsg struct {
+	seq       int // matches Model.delaySeq unless the test was replaced
+	groupName string
+	delays    map[string]int
+	err       error
+}
+
+type versionMsg struct {
+	version *clash.Version
+}
+
+type spinnerTickMsg struct{}
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+var errRequestCanceled = errors.New("request cancelled")
+
 func (m Model) Init() tea.Cmd {
-	return LoadProxiesCmd(m.Client)
+	return tea.Batch(reloadCmd, versionCmd(m.Backend), configCmd(m.Backend), openTrafficCmd(m.Backend), openMemoryCmd(m.Backend))
+}
+
+func reloadCmd() tea.Msg {
+	return reloadMsg{}
 }
 
 type proxiesLoadedMsg struct {
@@ -38,25 +71,66 @@ var (
 	activeProxyMarkStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("208")).Bold(true)
 	cursorStyle          = lipgloss.NewStyle().Foreground(lipgloss.Color("51")).Bold(true)
 	separatorStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	fastDelayStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+	mediumDelayStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+	slowDelayStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
 )
 
+// Options are the user settings that change how the TUI behaves.
+type Options struct {
+	// CloseConnectionsOnSwitch closes the connections going through a group
+	// after a proxy is selected in it.
+	CloseConnectionsOnSwitch bool
+
+	// MemoryThreshold turns the core memory indicator red above this many
+	// bytes; 0 disables it.
+	MemoryThreshold int64
+}
+
 type Model struct {
-	Client          *clash.Client
-	Proxies         map[string]clash.Proxy
-	Groups          []string
-	CurrentIdx      int
-	Cursor          int
-	Loading         bool
-	Err             error
-	ViewportOffset  int
-	Height          int    // Terminal height
-	lastCursorProxy string // Track proxy name at cursor to restore position after reload
-}
-
-func InitialModel() Model {
-	client := clash.NewClient("")
+	Backend          clash.Backend
+	Options          Options
+	Proxies          map[string]clash.Proxy
+	Groups           []string
+	CurrentIdx       int
+	Cursor           int
+	Loading          bool
+	Err              error
+	ViewportOffset   int
+	Height           int                // Terminal height
+	Width            int                // Terminal width, 0 when unknown
+	lastCursorProxy  string             // Track proxy name at cursor to restore position after reload
+	cancel           context.CancelFunc // Cancels the in-flight request, if any
+	Delays           map[string]int     // Latest delay test result by proxy name, 0 means failed
+	testingGroup     string             // Group whose delay test is running
+	delayCancel      context.CancelFunc // Cancels the running delay test
+	delaySeq         int                // Generation of the delay test, stale results are dropped
+	spinnerFrame     int
+	Version          *clash.Version // nil until /version answered
+	Conn             ConnState
+	reconnectAttempt int
+	reconnectDelay   time.Duration
+	page             page
+	conns            connectionsState
+	confirm          *confirmation // open y/N prompt, keys go to it first
+	notice           notice
+	traffic          trafficState
+	memory           memoryState
+	logs             logsState
+	rules            rulesState
+	providers        providersState
+	ruleProviders    ruleProvidersState
+	config           configState
+	mode             string // proxy mode of the core, "" until known
+	focusGlobal      bool   // focus GLOBAL once the groups are loaded
+	waitingCore      string // "reload" or "restart" while waiting for the core
+	restoreGroup     string // group to show again once the core is back
+}
+
+func InitialModel(backend clash.Backend, opts Options) Model {
 	return Model{
-		Client:          client,
+		Backend:         backend,
+		Options:         opts,
 		Proxies:         make(map[string]clash.Proxy),
 		Groups:          make([]string, 0),
 		CurrentIdx:      0,
@@ -66,55 +140,109 @@ func InitialModel() Model {
 		ViewportOffset:  0,
 		Height:          24,
 		lastCursorProxy: "",
+		Delays:          make(map[string]int),
+	}
+}
+
+// beginRequest cancels any in-flight request and returns the context for a
+// new one. Esc cancels it through m.cancel.
+func (m *Model) beginRequest() context.Context {
+	m.cancelRequest()
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	return ctx
+}
+
+func (m *Model) cancelRequest() {
+	if m.cancel != nil {
+		m.cancel()
+		m.cancel = nil
 	}
 }
 
-func LoadProxiesCmd(client *clash.Client) tea.Cmd {
+func LoadProxiesCmd(ctx context.Context, backend clash.Backend) tea.Cmd {
 	return func() tea.Msg {
-		proxies, err := client.GetProxies()
-		if err != nil {
-			return errMsg(err)
-		}
+		return loadProxies(ctx, backend)
+	}
+}
 
-		groups := make([]string, 0)
-		for name, proxy := range proxies.Proxies {
-			if proxy.Type == "Selector" || proxy.Type == "URLTest" {
-				groups = append(groups, name)
-			}
+func loadProxiesWithDelayCmd(ctx context.Context, backend clash.Backend) tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-ctx.Done():
+			return errMsg(ctx.Err())
 		}
+		return loadProxies(ctx, backend)
+	}
+}
 
-		// Sort groups alphabetically for consistent ordering
-		sort.Strings(groups)
+func loadProxies(ctx context.Context, backend clash.Backend) tea.Msg {
+	proxies, err := backend.GetProxiesContext(ctx)
+	if err != nil {
+		return errMsg(err)
+	}
 
-		return proxiesLoadedMsg{
-			proxies: proxies.Proxies,
-			groups:  groups,
+	groups := make([]string, 0)
+	for name, proxy := range proxies.Proxies {
+		if proxy.Type == "Selector" || proxy.Type == "URLTest" {
+			groups = append(groups, name)
 		}
 	}
+
+	// Sort groups alphabetically for consistent ordering
+	sort.Strings(groups)
+
+	return proxiesLoadedMsg{
+		proxies: proxies.Proxies,
+		groups:  groups,
+	}
 }
 
-func loadProxiesWithDelayCmd(client *clash.Client) tea.Cmd {
+func groupDelayCmd(ctx context.Context, backend clash.Backend, groupName string, seq int) tea.Cmd {
 	return func() tea.Msg {
-		time.Sleep(200 * time.Millisecond)
-
-		proxies, err := client.GetProxies()
-		if err != nil {
-			return errMsg(err)
+		delays, err := backend.GroupDelayContext(ctx, groupName, clash.DelayOptions{})
+		return groupDelayMsg{
+			seq:       seq,
+			groupName: groupName,
+			delays:    delays,
+			err:       err,
 		}
+	}
+}
 
-		groups := make([]string, 0)
-		for name, proxy := range proxies.Proxies {
-			if proxy.Type == "Selector" || proxy.Type == "URLTest" {
-				groups = append(groups, name)
-			}
+func spinnerTickCmd() tea.Cmd {
+	return tea.Tick(100*time.Millisecond, func(time.Time) tea.Msg {
+		return spinnerTickMsg{}
+	})
+}
+
+// versionCmd asks for the core version. Failures are dropped: the TUI then
+// assumes every capability.
+func versionCmd(backend clash.Backend) tea.Cmd {
+	return func() tea.Msg {
+		v, err := backend.GetVersionContext(context.Background())
+		if err != nil {
+			return nil
 		}
+		return versionMsg{version: v}
+	}
+}
 
-		// Sort groups alphabetically for consistent ordering
-		sort.Strings(groups)
+// caps returns what the connected core supports.
+// unsupported is the notice for a key whose feature the core lacks. Such
+// keys stay bound and answer with it, rather than silently doing nothing.
+func (m *Model) unsupported(feature string) tea.Cmd {
+	core := "this core"
+	if m.Version != nil {
+		core = m.Version.String()
+	}
+	return m.setNotice(fmt.Sprintf("%s: not supported by %s", feature, core), true)
+}
 
-		return proxiesLoadedMsg{
-			proxies: proxies.Proxies,
-			groups:  groups,
-		}
+func (m Model) caps() clash.Capabilities {
+	if m.Version == nil {
+		return clash.AllCapabilities
 	}
+	return m.Version.Capabilities()
 }
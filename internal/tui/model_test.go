@@ -1,8 +1,14 @@
 package tui
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
 
 	tea "charm.land/bubbletea/v2"
 	"github.com/wallacegibbon/proxy-controller-tui/internal/clash"
@@ -80,7 +86,7 @@ func TestURLTestWithoutFixed(t *testing.T) {
 func TestResetFixedKeyBinding(t *testing.T) {
 	// Test pressing 'a' key on URLTest group with fixed proxy
 	m := Model{
-		Client: clash.NewClient(""),
+		Backend: clash.NewMockBackend(),
 		Proxies: map[string]clash.Proxy{
 			"Auto": {
 				Name:  "Auto",
@@ -112,9 +118,83 @@ func TestResetFixedKeyBinding(t *testing.T) {
 	}
 }
 
+func TestResetFixedResult(t *testing.T) {
+	m := Model{
+		Backend: clash.NewMockBackend(),
+		Proxies: map[string]clash.Proxy{
+			"Auto": {Name: "Auto", Type: "URLTest", Now: "Auto-2", Fixed: "Auto-2", All: []string{"Auto-1", "Auto-2"}},
+		},
+		Groups:  []string{"Auto"},
+		Loading: true,
+		Height:  24,
+	}
+
+	// A reset abandoned with Esc does not reload
+	if _, cmd := m.Update(resetFixedMsg{groupName: "Auto", err: context.Canceled}); cmd != nil {
+		t.Errorf("Expected no reload after a cancelled reset")
+	}
+
+	// Failures reach the error screen instead of being dropped
+	newModel, cmd := m.Update(resetFixedMsg{groupName: "Auto", err: &clash.GroupNotFoundError{Group: "Auto"}})
+	if m2 := newModel.(Model); cmd != nil || m2.Loading || m2.Err == nil {
+		t.Errorf("Expected the error to be shown, got Err %v", m2.Err)
+	}
+
+	// Success reloads, cancellable with Esc
+	newModel, cmd = m.Update(resetFixedMsg{groupName: "Auto"})
+	if m2 := newModel.(Model); cmd == nil || !m2.Loading || m2.cancel == nil {
+		t.Errorf("Expected a cancellable reload after the reset")
+	}
+}
+
+// hangingBackend never answers SelectProxy, like a controller that stopped
+// responding. The request only ends when its context is cancelled.
+type hangingBackend struct {
+	*clash.MemoryBackend
+}
+
+func (hangingBackend) SelectProxyContext(ctx context.Context, groupName, proxyName string) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestEscCancelsSelectProxy(t *testing.T) {
+	m := Model{
+		Backend: hangingBackend{clash.NewMockBackend()},
+		Proxies: map[string]clash.Proxy{
+			"Proxy": {Name: "Proxy", Type: "Selector", Now: "Proxy-1", All: []string{"Proxy-1", "Proxy-2"}},
+		},
+		Groups: []string{"Proxy"},
+		Height: 24,
+	}
+
+	newModel, cmd := m.Update(tea.KeyPressMsg(tea.Key{Code: tea.KeyEnter}))
+	if cmd == nil {
+		t.Fatalf("Expected Enter to select the proxy")
+	}
+	done := make(chan tea.Msg, 1)
+	go func() { done <- cmd() }()
+
+	newModel, _ = newModel.Update(tea.KeyPressMsg(tea.Key{Code: tea.KeyEscape}))
+	var msg tea.Msg
+	select {
+	case msg = <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Expected Esc to cancel the hanging request")
+	}
+	if sel, ok := msg.(selectProxyMsg); !ok || !errors.Is(sel.err, context.Canceled) {
+		t.Fatalf("Expected a cancelled selectProxyMsg, got %#v", msg)
+	}
+
+	newModel, _ = newModel.Update(msg)
+	if m2 := newModel.(Model); m2.Err != nil || !strings.Contains(m2.View().Content, "Proxy-2") {
+		t.Errorf("Expected the proxy list to stay after cancelling, got Err %v", m2.Err)
+	}
+}
+
 func TestCursorMovement(t *testing.T) {
 	m := Model{
-		Client: clash.NewClient(""),
+		Backend: clash.NewMockBackend(),
 		Proxies: map[string]clash.Proxy{
 			"Proxy": {
 				Name: "Proxy",
@@ -358,3 +438,1200 @@ func TestManyGroupsExceedsTerminalHeight(t *testing.T) {
 		t.Errorf("Selected group 'GroupK' should be visible:\n%s", out)
 	}
 }
+
+func TestEscCancelsLoading(t *testing.T) {
+	m := Model{
+		Backend: clash.NewMockBackend(),
+		Proxies: map[string]clash.Proxy{},
+		Groups:  []string{},
+		Height:  24,
+	}
+
+	newModel, cmd := m.Update(reloadMsg{})
+	m2 := newModel.(Model)
+	if !m2.Loading || cmd == nil || m2.cancel == nil {
+		t.Fatalf("Expected a cancellable load to start")
+	}
+
+	// Other keys are ignored while loading
+	newModel, _ = m2.Update(tea.KeyPressMsg(tea.Key{Text: "j", Code: 'j'}))
+	if !newModel.(Model).Loading {
+		t.Errorf("Expected to still be loading after 'j'")
+	}
+
+	newModel, _ = m2.Update(tea.KeyPressMsg(tea.Key{Code: tea.KeyEscape}))
+	m3 := newModel.(Model)
+	if m3.Loading {
+		t.Errorf("Expected Esc to stop loading")
+	}
+	if m3.Err != errRequestCanceled {
+		t.Errorf("Expected cancelled error with no data loaded, got %v", m3.Err)
+	}
+
+	// The cancelled command's error must not replace the view state
+	newModel, _ = m3.Update(errMsg(context.Canceled))
+	if newModel.(Model).Err != errRequestCanceled {
+		t.Errorf("Expected context.Canceled from the aborted request to be ignored")
+	}
+}
+
+func TestGroupDelayTest(t *testing.T) {
+	m := Model{
+		Backend: clash.NewMockBackend(),
+		Proxies: map[string]clash.Proxy{
+			"Proxy": {
+				Name: "Proxy",
+				Type: "Selector",
+				Now:  "Proxy-1",
+				All:  []string{"Proxy-1", "Proxy-2", "Proxy-3"},
+			},
+		},
+		Groups: []string{"Proxy"},
+		Height: 24,
+	}
+
+	newModel, cmd := m.Update(tea.KeyPressMsg(tea.Key{Text: "t", Code: 't'}))
+	m2 := newModel.(Model)
+	if m2.testingGroup != "Proxy" || cmd == nil {
+		t.Fatalf("Expected delay test to start for group 'Proxy'")
+	}
+	if out := m2.View().Content; !strings.Contains(out, "testing") {
+		t.Errorf("Expected progress indicator while testing, got:\n%s", out)
+	}
+
+	// Proxy-3 is missing from the result, so it failed
+	newModel, _ = m2.Update(groupDelayMsg{
+		seq:       m2.delaySeq,
+		groupName: "Proxy",
+		delays:    map[string]int{"Proxy-1": 87, "Proxy-2": 640},
+	})
+	m3 := newModel.(Model)
+	if m3.testingGroup != "" {
+		t.Errorf("Expected testing to finish")
+	}
+	out := m3.View().Content
+	t.Logf("View output:\n%s", out)
+	for _, want := range []string{"87ms", "640ms", "timeout"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in output", want)
+		}
+	}
+}
+
+func TestGroupDelayStaleAndFailed(t *testing.T) {
+	m := Model{
+		Backend: clash.NewMockBackend(),
+		Proxies: map[string]clash.Proxy{
+			"Proxy": {Name: "Proxy", Type: "Selector", Now: "Proxy-1", All: []string{"Proxy-1", "Proxy-2"}},
+		},
+		Groups: []string{"Proxy"},
+		Height: 24,
+	}
+	press := func(m Model, k tea.Key) Model {
+		newModel, _ := m.Update(tea.KeyPressMsg(k))
+		return newModel.(Model)
+	}
+
+	// Cancel the first test and start another one
+	m = press(m, tea.Key{Text: "t", Code: 't'})
+	first := m.delaySeq
+	m = press(m, tea.Key{Code: tea.KeyEscape})
+	m = press(m, tea.Key{Text: "t", Code: 't'})
+
+	// The first test's late answer leaves the running one alone
+	newModel, _ := m.Update(groupDelayMsg{seq: first, groupName: "Proxy", err: context.Canceled})
+	m = newModel.(Model)
+	if m.testingGroup != "Proxy" || m.delayCancel == nil {
+		t.Fatalf("Expected the stale result to be ignored")
+	}
+
+	// A failed test is a notice; the proxy list stays
+	newModel, cmd := m.Update(groupDelayMsg{
+		seq:       m.delaySeq,
+		groupName: "Proxy",
+		err:       &clash.StatusError{StatusCode: 504, Message: "Timeout"},
+	})
+	m = newModel.(Model)
+	if m.testingGroup != "" || m.Err != nil || cmd == nil {
+		t.Fatalf("Expected the test to end with a notice, got Err %v", m.Err)
+	}
+	out := m.View().Content
+	for _, want := range []string{"Delay test of Proxy failed", "Proxy-2"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestSelectProxyUsesBackend(t *testing.T) {
+	backend := clash.NewMemoryBackend(map[string]clash.Proxy{
+		"Proxy": {
+			Name: "Proxy",
+			Type: "Selector",
+			Now:  "Proxy-1",
+			All:  []string{"Proxy-1", "Proxy-2"},
+		},
+	})
+	m := InitialModel(backend, Options{})
+
+	// Run the initial load against the in-memory backend
+	newModel, cmd := m.Update(reloadMsg{})
+	newModel, _ = newModel.Update(cmd())
+	m2 := newModel.(Model)
+	if len(m2.Groups) != 1 || m2.Cursor != 0 {
+		t.Fatalf("Expected one group with cursor on active proxy, got %v cursor %d", m2.Groups, m2.Cursor)
+	}
+
+	newModel, _ = m2.Update(tea.KeyPressMsg(tea.Key{Text: "j", Code: 'j'}))
+	newModel, cmd = newModel.Update(tea.KeyPressMsg(tea.Key{Code: tea.KeyEnter}))
+	// The list stays on screen until the background reload refreshes it
+	if m3 := newModel.(Model); m3.Loading || !strings.Contains(m3.View().Content, "Proxy-2") {
+		t.Errorf("Expected the proxy list to stay visible while selecting")
+	}
+	msg := cmd()
+	if sel, ok := msg.(selectProxyMsg); !ok || sel.err != nil {
+		t.Fatalf("Expected successful selectProxyMsg, got %#v", msg)
+	}
+
+	proxies, _ := backend.GetProxiesContext(context.Background())
+	if now := proxies.Proxies["Proxy"].Now; now != "Proxy-2" {
+		t.Errorf("Expected backend to have Proxy-2 selected, got %q", now)
+	}
+}
+
+func TestErrorHints(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{&clash.UnauthorizedError{}, "MIHOMO_SECRET"},
+		{&clash.UnreachableError{Address: "http://127.0.0.1:9090", Err: syscall.ECONNREFUSED}, "external-controller enabled"},
+		{&clash.UnreachableError{Address: "unix:///tmp/x.sock", Err: os.ErrNotExist}, "external-controller-unix"},
+		{&clash.GroupNotFoundError{Group: "Gone"}, "reload"},
+		{&clash.DecodeError{Err: errors.New("bad")}, "Clash/Mihomo controller"},
+	}
+	for _, tt := range tests {
+		m := Model{Err: tt.err, Height: 24}
+		out := m.View().Content
+		if !strings.Contains(out, tt.want) {
+			t.Errorf("%T: expected hint containing %q, got:\n%s", tt.err, tt.want, out)
+		}
+	}
+}
+
+func TestErrorScreenKeys(t *testing.T) {
+	m := Model{Backend: clash.NewMockBackend(), Err: &clash.UnauthorizedError{}, Height: 24}
+
+	// Global keys do nothing behind the error screen, nor leave a prompt
+	for _, k := range []tea.Key{
+		{Text: "m", Code: 'm'},
+		{Text: "R", Code: 'r', Mod: tea.ModShift},
+		{Code: 'r', Mod: tea.ModCtrl},
+		{Text: "F", Code: 'f', Mod: tea.ModShift},
+		{Text: "D", Code: 'd', Mod: tea.ModShift},
+		{Code: tea.KeyTab},
+		{Text: "2", Code: '2'},
+	} {
+		newModel, cmd := m.Update(tea.KeyPressMsg(k))
+		m2 := newModel.(Model)
+		if cmd != nil || m2.confirm != nil || m2.page != pageProxies {
+			t.Errorf("Expected %q to be ignored on the error screen", k.String())
+		}
+	}
+
+	if _, cmd := m.Update(tea.KeyPressMsg(tea.Key{Text: "q", Code: 'q'})); cmd == nil {
+		t.Fatalf("Expected q to quit")
+	} else if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Errorf("Expected q to quit")
+	}
+	newModel, cmd := m.Update(tea.KeyPressMsg(tea.Key{Text: "r", Code: 'r'}))
+	if !newModel.(Model).Loading || cmd == nil {
+		t.Errorf("Expected r to retry")
+	}
+}
+
+func TestReconnectKeepsLastProxies(t *testing.T) {
+	m := Model{
+		Backend: clash.NewMockBackend(),
+		Proxies: map[string]clash.Proxy{
+			"Proxy": {
+				Name: "Proxy",
+				Type: "Selector",
+				Now:  "Proxy-1",
+				All:  []string{"Proxy-1", "Proxy-2"},
+			},
+		},
+		Groups: []string{"Proxy"},
+		Height: 10,
+	}
+
+	lost := &clash.UnreachableError{Address: "http://127.0.0.1:9090", Err: syscall.ECONNREFUSED}
+	newModel, cmd := m.Update(errMsg(lost))
+	m2 := newModel.(Model)
+	if m2.Err != nil || m2.Conn != Reconnecting || cmd == nil {
+		t.Fatalf("Expected reconnecting without error screen, got conn=%v err=%v", m2.Conn, m2.Err)
+	}
+	out := m2.View().Content
+	if !strings.Contains(out, "retrying in 1s") || !strings.Contains(out, "Proxy-1") {
+		t.Errorf("Expected banner above the last known proxies, got:\n%s", out)
+	}
+	if lines := strings.Split(out, "\n"); len(lines) > m2.Height+1 {
+		t.Errorf("Output exceeds terminal height with banner: %d lines", len(lines)-1)
+	}
+
+	// Backoff grows and eventually goes offline
+	for i := 0; i < maxReconnectAttempts; i++ {
+		newModel, _ = newModel.Update(errMsg(lost))
+	}
+	m3 := newModel.(Model)
+	if m3.Conn != Offline || m3.reconnectDelay <= initialReconnectDelay {
+		t.Errorf("Expected offline with a longer delay, got conn=%v delay=%v", m3.Conn, m3.reconnectDelay)
+	}
+
+	// Stale ticks are ignored, the current one probes the backend
+	if _, cmd := m3.Update(reconnectMsg{attempt: 1}); cmd != nil {
+		t.Errorf("Expected stale reconnect tick to be ignored")
+	}
+	newModel, cmd = m3.Update(reconnectMsg{attempt: m3.reconnectAttempt})
+	if cmd == nil {
+		t.Fatalf("Expected current reconnect tick to probe the controller")
+	}
+	if newModel.(Model).cancel == nil {
+		t.Errorf("Expected the probe to be tracked as the in-flight request")
+	}
+	newModel, _ = newModel.Update(cmd())
+	if m4 := newModel.(Model); m4.Conn != Connected || m4.reconnectAttempt != 0 {
+		t.Errorf("Expected connection to be restored, got conn=%v", m4.Conn)
+	}
+
+	// Errors that retrying cannot fix still go to the error screen
+	newModel, _ = m.Update(errMsg(&clash.UnauthorizedError{}))
+	if m5 := newModel.(Model); m5.Err == nil || m5.Conn != Connected {
+		t.Errorf("Expected 401 on the error screen without reconnecting")
+	}
+}
+
+func TestReconnectBackoff(t *testing.T) {
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 30 * time.Second, 30 * time.Second}
+	for i, w := range want {
+		if got := reconnectBackoff(i + 1); got != w {
+			t.Errorf("attempt %d: expected %v, got %v", i+1, w, got)
+		}
+	}
+}
+
+func TestVersionCapabilities(t *testing.T) {
+	m := Model{
+		Backend: clash.NewMockBackend(),
+		Proxies: map[string]clash.Proxy{
+			"Auto": {
+				Name:  "Auto",
+				Type:  "URLTest",
+				Now:   "Auto-2",
+				Fixed: "Auto-2",
+				All:   []string{"Auto-1", "Auto-2"},
+			},
+		},
+		Groups: []string{"Auto"},
+		Height: 24,
+	}
+
+	newModel, _ := m.Update(versionMsg{version: &clash.Version{Version: "v1.19.0", Meta: true}})
+	if out := newModel.(Model).View().Content; !strings.Contains(out, "Mihomo v1.19.0") {
+		t.Errorf("Expected version in header, got:\n%s", out)
+	}
+
+	// Plain Clash has neither group delay nor DELETE to unfix; the keys
+	// say so instead of sending requests
+	newModel, _ = m.Update(versionMsg{version: &clash.Version{Version: "v1.18.0"}})
+	m2 := newModel.(Model)
+	for _, k := range []tea.Key{{Text: "t", Code: 't'}, {Text: "a", Code: 'a'}} {
+		newModel, _ := m2.Update(tea.KeyPressMsg(k))
+		m3 := newModel.(Model)
+		if m3.Loading || m3.testingGroup != "" || !strings.Contains(m3.notice.text, ": not supported by Clash v1.18.0") {
+			t.Errorf("Expected %q to be disabled with a notice, got %q", k.Text, m3.notice.text)
+		}
+	}
+
+	// Nor a provider health check before v0.18
+	m2.Version = &clash.Version{Version: "v0.17.1"}
+	m2.page = pageProviders
+	m2.providers = providersState{loaded: true, list: []clash.ProxyProvider{{Name: "sub", VehicleType: "HTTP"}}}
+	newModel, _ = m2.Update(tea.KeyPressMsg(tea.Key{Text: "t", Code: 't'}))
+	if m3 := newModel.(Model); len(m3.providers.busy) != 0 || m3.notice.text != "Provider health checks: not supported by Clash v0.17.1" {
+		t.Errorf("Expected the health check to be disabled, got %q", m3.notice.text)
+	}
+
+	// A lost unix socket hints at TCP when the core cannot listen on one
+	m2 = Model{
+		Version: &clash.Version{Version: "v1.17.0", Meta: true},
+		Err:     &clash.UnreachableError{Address: "unix:///tmp/x.sock", Err: os.ErrNotExist},
+		Height:  24,
+	}
+	if out := m2.View().Content; !strings.Contains(out, "has no external-controller-unix") {
+		t.Errorf("Expected the unix socket hint, got:\n%s", out)
+	}
+}
+
+func TestConnectionsPage(t *testing.T) {
+	backend := clash.NewMemoryBackend(nil)
+	now := time.Now()
+	backend.AddConnection(clash.Connection{
+		ID:       "old",
+		Metadata: clash.ConnectionMetadata{Host: "old.example.com", DestinationPort: "443", Network: "tcp"},
+		Upload:   10,
+		Download: 20,
+		Start:    now.Add(-time.Hour),
+		Chains:   []string{"Proxy-1", "Proxy"},
+		Rule:     "Match",
+	})
+	backend.AddConnection(clash.Connection{
+		ID:          "busy",
+		Metadata:    clash.ConnectionMetadata{Host: "busy.example.com", DestinationPort: "443", Network: "tcp"},
+		Upload:      1 << 20,
+		Download:    5 << 20,
+		Start:       now.Add(-time.Minute),
+		Chains:      []string{"Proxy-2", "Proxy"},
+		Rule:        "DomainSuffix",
+		RulePayload: "example.com",
+	})
+
+	m := Model{Backend: backend, Proxies: map[string]clash.Proxy{}, Height: 24}
+
+	// Tab switches pages and starts the refresh loop
+	newModel, cmd := m.Update(tea.KeyPressMsg(tea.Key{Code: tea.KeyTab}))
+	m2 := newModel.(Model)
+	if m2.page != pageConnections || cmd == nil {
+		t.Fatalf("Expected Tab to open the Connections page with a fetch")
+	}
+	newModel, tick := m2.Update(cmd())
+	m3 := newModel.(Model)
+	if tick == nil {
+		t.Errorf("Expected a refresh tick to be scheduled")
+	}
+
+	// Sorted by traffic: the busy connection comes first
+	if len(m3.conns.list) != 2 || m3.conns.list[0].ID != "busy" {
+		t.Fatalf("Expected busy connection first, got %+v", m3.conns.list)
+	}
+	out := m3.View().Content
+	t.Logf("View output:\n%s", out)
+	for _, want := range []string{"2 connections", "busy.example.com:443", "Proxy → Proxy-2", "DomainSuffix(example.com)", "5.0 MB"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in output", want)
+		}
+	}
+
+	// Sorting by age keeps the cursor on the same connection
+	newModel, _ = m3.Update(tea.KeyPressMsg(tea.Key{Text: "s", Code: 's'}))
+	m4 := newModel.(Model)
+	if m4.conns.sortBy != sortByAge || m4.conns.list[0].ID != "old" {
+		t.Errorf("Expected oldest connection first when sorting by age")
+	}
+	if m4.conns.list[m4.conns.cursor].ID != "busy" {
+		t.Errorf("Expected cursor to follow the busy connection")
+	}
+
+	// Leaving the page retires the refresh loop
+	newModel, _ = m4.Update(tea.KeyPressMsg(tea.Key{Text: "1", Code: '1'}))
+	if _, cmd := newModel.Update(connectionsTickMsg{seq: m4.conns.seq}); cmd != nil {
+		t.Errorf("Expected refresh to stop after leaving the page")
+	}
+}
+
+func TestCloseConnections(t *testing.T) {
+	backend := clash.NewMemoryBackend(nil)
+	for _, id := range []string{"a", "b", "c"} {
+		host := id + ".example.com"
+		if id == "c" {
+			host = "c.other.org"
+		}
+		backend.AddConnection(clash.Connection{
+			ID:       id,
+			Metadata: clash.ConnectionMetadata{Host: host, DestinationPort: "443"},
+			Chains:   []string{"Proxy"},
+		})
+	}
+	count := func() int {
+		resp, _ := backend.GetConnectionsContext(context.Background())
+		return len(resp.Connections)
+	}
+	press := func(m Model, key tea.Key) (Model, tea.Cmd) {
+		newModel, cmd := m.Update(tea.KeyPressMsg(key))
+		return newModel.(Model), cmd
+	}
+
+	m := Model{Backend: backend, Proxies: map[string]clash.Proxy{}, Height: 24}
+	m, cmd := press(m, tea.Key{Code: tea.KeyTab})
+	newModel, _ := m.Update(cmd())
+	m = newModel.(Model)
+
+	// Typing a filter narrows the list; the page keys are not triggered
+	m, _ = press(m, tea.Key{Text: "/", Code: '/'})
+	for _, r := range "example" {
+		m, _ = press(m, tea.Key{Text: string(r), Code: r})
+	}
+	m, _ = press(m, tea.Key{Code: tea.KeyEnter})
+	if m.conns.filter.active || len(m.conns.list) != 2 {
+		t.Fatalf("Expected 2 connections matching the filter, got %d", len(m.conns.list))
+	}
+
+	// Bulk close asks first; anything but y cancels
+	m, cmd = press(m, tea.Key{Text: "X", Code: 'x', Mod: tea.ModShift})
+	if m.confirm == nil || cmd != nil {
+		t.Fatalf("Expected a confirmation prompt for closing filtered connections")
+	}
+	if out := m.View().Content; !strings.Contains(out, `Close 2 connections matching "example"?`) {
+		t.Errorf("Expected the prompt in output:\n%s", out)
+	}
+	m, cmd = press(m, tea.Key{Text: "n", Code: 'n'})
+	if m.confirm != nil || cmd != nil || count() != 3 {
+		t.Fatalf("Expected n to cancel the close")
+	}
+
+	m, _ = press(m, tea.Key{Text: "X", Code: 'x', Mod: tea.ModShift})
+	m, cmd = press(m, tea.Key{Text: "y", Code: 'y'})
+	if cmd == nil {
+		t.Fatalf("Expected y to close the connections")
+	}
+	newModel, _ = m.Update(cmd())
+	m = newModel.(Model)
+	if count() != 1 {
+		t.Errorf("Expected only the unmatched connection left, got %d", count())
+	}
+	if !strings.Contains(m.notice.text, "Closed 2 connections") {
+		t.Errorf("Expected a notice about closed connections, got %q", m.notice.text)
+	}
+
+	// Esc clears the filter; x closes the connection under the cursor
+	m, _ = press(m, tea.Key{Text: "/", Code: '/'})
+	m, _ = press(m, tea.Key{Code: tea.KeyEscape})
+	if m.conns.filter.value != "" {
+		t.Errorf("Expected Esc to clear the filter")
+	}
+	m.conns.all = []clash.Connection{{ID: "c"}}
+	m.applyConnections()
+	m, cmd = press(m, tea.Key{Text: "x", Code: 'x'})
+	if cmd == nil {
+		t.Fatalf("Expected x to close the selected connection")
+	}
+	cmd()
+	if count() != 0 {
+		t.Errorf("Expected all connections closed, got %d", count())
+	}
+}
+
+func TestCloseConnectionsOnSwitch(t *testing.T) {
+	backend := clash.NewMemoryBackend(map[string]clash.Proxy{
+		"Proxy": {Name: "Proxy", Type: "Selector", Now: "Proxy-1", All: []string{"Proxy-1", "Proxy-2"}},
+	})
+	backend.AddConnection(clash.Connection{ID: "through", Chains: []string{"Proxy-1", "Proxy"}})
+	backend.AddConnection(clash.Connection{ID: "direct", Chains: []string{"DIRECT"}})
+
+	m := InitialModel(backend, Options{CloseConnectionsOnSwitch: true})
+	m.Loading = false
+	m.Proxies, m.Groups = map[string]clash.Proxy{"Proxy": {Name: "Proxy"}}, []string{"Proxy"}
+
+	_, cmd := m.Update(selectProxyMsg{groupName: "Proxy", proxyName: "Proxy-2"})
+	batch, ok := cmd().(tea.BatchMsg)
+	if !ok || len(batch) != 2 {
+		t.Fatalf("Expected reload and close commands, got %#v", batch)
+	}
+	closed, ok := batch[1]().(connectionsClosedMsg)
+	if !ok || closed.closed != 1 || closed.err != nil {
+		t.Fatalf("Expected one connection closed, got %#v", closed)
+	}
+
+	resp, _ := backend.GetConnectionsContext(context.Background())
+	if len(resp.Connections) != 1 || resp.Connections[0].ID != "direct" {
+		t.Errorf("Expected only the direct connection left, got %+v", resp.Connections)
+	}
+
+	newModel, _ := m.Update(closed)
+	if text := newModel.(Model).notice.text; text != "Closed 1 connection through Proxy" {
+		t.Errorf("Expected a notice with the count, got %q", text)
+	}
+}
+
+func TestTrafficStatusLine(t *testing.T) {
+	m := Model{Backend: clash.NewMemoryBackend(nil), Proxies: map[string]clash.Proxy{}, Height: 24}
+
+	newModel, cmd := m.Update(openTrafficCmd(m.Backend)())
+	m = newModel.(Model)
+	if m.traffic.stream == nil || cmd == nil {
+		t.Fatalf("Expected the stream to open and wait for a sample")
+	}
+	defer m.traffic.stream.Close()
+
+	for _, down := range []int64{0, 512 << 10, 1 << 20} {
+		newModel, cmd = m.Update(trafficMsg{sample: clash.Traffic{Up: 2048, Down: down}})
+		m = newModel.(Model)
+		if cmd == nil {
+			t.Fatalf("Expected the next sample to be requested")
+		}
+	}
+	line := m.trafficLine()
+	t.Logf("Traffic line: %s", line)
+	for _, want := range []string{"↑ 2.0 KB/s ███", "↓ 1.0 MB/s ▁▄█"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("Expected %q in %q", want, line)
+		}
+	}
+	if lines := m.headerLines(); len(lines) != 2 {
+		t.Errorf("Expected the traffic line below the tabs, got %d header lines", len(lines))
+	}
+
+	// A controller without /traffic hides the line for good
+	m2 := Model{}
+	newModel, cmd = m2.Update(trafficOpenedMsg{err: &clash.StatusError{StatusCode: 404}})
+	if !newModel.(Model).traffic.unsupported || cmd != nil {
+		t.Errorf("Expected a 404 to disable the traffic stream")
+	}
+}
+
+func TestMemoryIndicator(t *testing.T) {
+	m := Model{Options: Options{MemoryThreshold: 100 << 20}}
+	stream, _ := clash.NewMemoryBackend(nil).StreamMemoryContext(context.Background())
+	defer stream.Close()
+	m.memory.stream = stream
+
+	// The leading zero sample is skipped
+	for _, inuse := range []int64{0, 50 << 20, 60 << 20, 80 << 20} {
+		newModel, cmd := m.Update(memoryMsg{sample: clash.MemoryUsage{InUse: inuse}})
+		m = newModel.(Model)
+		if cmd == nil {
+			t.Fatalf("Expected the next sample to be requested")
+		}
+	}
+	if len(m.memory.history) != 3 {
+		t.Fatalf("Expected 3 samples, got %v", m.memory.history)
+	}
+	line := m.tabsLine()
+	t.Logf("Tabs line: %s", line)
+	if !strings.Contains(line, "mem 80.0 MB") || !strings.Contains(line, "↑") {
+		t.Errorf("Expected memory use with a rising trend in %q", line)
+	}
+	if got := m.memoryIndicator(); got != memoryStyle.Render("mem 80.0 MB ▅▆█ ↑") {
+		t.Errorf("Expected normal style below the threshold, got %q", got)
+	}
+
+	newModel, _ := m.Update(memoryMsg{sample: clash.MemoryUsage{InUse: 120 << 20}})
+	m = newModel.(Model)
+	if got := m.memoryIndicator(); got != memoryHighStyle.Render("mem 120.0 MB ▃▄▅█ ↑") {
+		t.Errorf("Expected warning style above the threshold, got %q", got)
+	}
+}
+
+func TestLogsPage(t *testing.T) {
+	m := Model{Backend: clash.NewMemoryBackend(nil), Proxies: map[string]clash.Proxy{}, Height: 10}
+	press := func(key tea.Key) tea.Cmd {
+		newModel, cmd := m.Update(tea.KeyPressMsg(key))
+		m = newModel.(Model)
+		return cmd
+	}
+	send := func(level, text string) {
+		newModel, _ := m.Update(logMsg{seq: m.logs.seq, entry: clash.LogEntry{Type: level, Payload: text}})
+		m = newModel.(Model)
+	}
+
+	cmd := press(tea.Key{Text: "3", Code: '3'})
+	if m.page != pageLogs || cmd == nil {
+		t.Fatalf("Expected 3 to open the Logs page and its stream")
+	}
+	opened := cmd().(logsOpenedMsg)
+	defer opened.stream.Close()
+	newModel, _ := m.Update(opened)
+	m = newModel.(Model)
+
+	for i := 0; i < 20; i++ {
+		send(clash.LogInfo, fmt.Sprintf("line %d", i))
+	}
+	send(clash.LogWarning, "dial Proxy-2 error: i/o timeout")
+	lines := m.visibleLogs()
+	if len(lines) != 21 || m.logs.cursor != 20 {
+		t.Fatalf("Expected to follow the newest of 21 lines, cursor at %d", m.logs.cursor)
+	}
+
+	// Pausing holds new lines back until resumed
+	press(tea.Key{Code: tea.KeySpace, Text: " "})
+	send(clash.LogInfo, "while paused")
+	if len(m.visibleLogs()) != 21 || !strings.Contains(m.View().Content, "paused (1 new)") {
+		t.Errorf("Expected the line to be held while paused")
+	}
+	press(tea.Key{Code: tea.KeySpace, Text: " "})
+	if len(m.visibleLogs()) != 22 || m.logs.cursor != 21 {
+		t.Errorf("Expected held lines to show after resuming")
+	}
+
+	// Incremental search jumps to the match and highlights it
+	press(tea.Key{Code: tea.KeyHome})
+	press(tea.Key{Text: "/", Code: '/'})
+	for _, r := range "PROXY-2" {
+		press(tea.Key{Text: string(r), Code: r})
+	}
+	if got := m.visibleLogs()[m.logs.cursor].text; !strings.Contains(got, "Proxy-2") {
+		t.Errorf("Expected the cursor on the match, got %q", got)
+	}
+	if out := m.View().Content; !strings.Contains(out, logHighlightStyle.Render("Proxy-2")) {
+		t.Errorf("Expected the match to be highlighted:\n%s", out)
+	}
+	press(tea.Key{Code: tea.KeyEnter})
+
+	// Raising the level hides buffered lines below it and restarts the stream
+	cmd = press(tea.Key{Text: "L", Code: 'l', Mod: tea.ModShift})
+	if m.logs.level != clash.LogWarning || cmd == nil {
+		t.Fatalf("Expected L to switch to warning and reopen the stream")
+	}
+	if lines := m.visibleLogs(); len(lines) != 1 {
+		t.Errorf("Expected only the warning visible, got %d lines", len(lines))
+	}
+	newModel, _ = m.Update(logMsg{seq: m.logs.seq - 1, entry: clash.LogEntry{Type: clash.LogInfo, Payload: "stale"}})
+	if len(newModel.(Model).logs.buf.lines) != 22 {
+		t.Errorf("Expected lines from the old stream to be dropped")
+	}
+
+	// w saves the whole buffer, whatever the level
+	t.Chdir(t.TempDir())
+	saved := press(tea.Key{Text: "w", Code: 'w'})().(logsSavedMsg)
+	data, err := os.ReadFile(saved.path)
+	if err != nil || strings.Count(string(data), "\n") != 22 || !strings.Contains(string(data), "[WARNING] dial Proxy-2") {
+		t.Errorf("Expected 22 lines saved, got %v:\n%s", err, data)
+	}
+	newModel, _ = m.Update(saved)
+	if text := newModel.(Model).notice.text; !strings.Contains(text, saved.path) {
+		t.Errorf("Expected the path in the notice, got %q", text)
+	}
+}
+
+func TestHighlightFold(t *testing.T) {
+	tests := []struct {
+		text, query, match string
+	}{
+		{"dial Proxy-2 failed", "proxy-2", "Proxy-2"},
+		{"Ärger über Äpfel", "äRGER", "Ärger"},
+		// Lowering changes the byte length of these runes
+		{"rule Ⱥ matched", "ⱥ", "Ⱥ"},
+		{"Kelvin", "k", "K"},
+	}
+	for _, tt := range tests {
+		if !containsFold(tt.text, tt.query) {
+			t.Errorf("Expected %q to contain %q", tt.text, tt.query)
+		}
+		if got := highlight(tt.text, tt.query); !strings.Contains(got, logHighlightStyle.Render(tt.match)) {
+			t.Errorf("Expected %q highlighted in %q, got %q", tt.match, tt.text, got)
+		}
+	}
+
+	// Every occurrence is marked and the text is kept whole
+	got := highlight("ⱥ-Ⱥ-ⱥ", "Ⱥ")
+	if want := logTextStyle.Render("") + logHighlightStyle.Render("ⱥ") + logTextStyle.Render("-") +
+		logHighlightStyle.Render("Ⱥ") + logTextStyle.Render("-") + logHighlightStyle.Render("ⱥ") +
+		logTextStyle.Render(""); got != want {
+		t.Errorf("Unexpected highlighting %q", got)
+	}
+}
+
+func TestLogRing(t *testing.T) {
+	var r logRing
+	for i := 0; i < logBufferSize+5; i++ {
+		r.push(logLine{text: fmt.Sprint(i)})
+	}
+	all := r.all()
+	if len(all) != logBufferSize || all[0].text != "5" || all[len(all)-1].text != fmt.Sprint(logBufferSize+4) {
+		t.Errorf("Expected the newest %d lines in order, got %s..%s", logBufferSize, all[0].text, all[len(all)-1].text)
+	}
+}
+
+func TestRulesPage(t *testing.T) {
+	backend := clash.NewMemoryBackend(clash.MockProxies())
+	backend.SetRules(clash.MockRules())
+	m := InitialModel(backend, Options{})
+	newModel, cmd := m.Update(reloadMsg{})
+	newModel, _ = newModel.Update(cmd())
+	m = newModel.(Model)
+	press := func(key tea.Key) tea.Cmd {
+		newModel, cmd := m.Update(tea.KeyPressMsg(key))
+		m = newModel.(Model)
+		return cmd
+	}
+
+	cmd = press(tea.Key{Text: "4", Code: '4'})
+	if m.page != pageRules || cmd == nil {
+		t.Fatalf("Expected 4 to open the Rules page and load the rules")
+	}
+	newModel, _ = m.Update(cmd())
+	m = newModel.(Model)
+	if len(m.rules.list) != len(clash.MockRules()) {
+		t.Fatalf("Expected all rules listed, got %d", len(m.rules.list))
+	}
+	out := m.View().Content
+	for _, want := range []string{"RuleSet", "streaming", "1532 rules", "Proxy Group B"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in output:\n%s", want, out)
+		}
+	}
+
+	// Filter by target group, then by type
+	press(tea.Key{Text: "/", Code: '/'})
+	for _, r := range "group:group b" {
+		press(tea.Key{Text: string(r), Code: r})
+	}
+	if len(m.rules.list) != 2 {
+		t.Errorf("Expected 2 rules targeting Proxy Group B, got %d", len(m.rules.list))
+	}
+	press(tea.Key{Code: tea.KeyEscape})
+	press(tea.Key{Text: "/", Code: '/'})
+	for _, r := range "type:domainsuffix" {
+		press(tea.Key{Text: string(r), Code: r})
+	}
+	press(tea.Key{Code: tea.KeyEnter})
+	if len(m.rules.list) != 3 {
+		t.Errorf("Expected 3 DomainSuffix rules, got %d", len(m.rules.list))
+	}
+
+	// Enter on a rule to DIRECT is not a group
+	press(tea.Key{Code: tea.KeyEnd})
+	press(tea.Key{Code: tea.KeyEnter})
+	if m.page != pageRules || !strings.Contains(m.notice.text, "DIRECT is not a proxy group") {
+		t.Errorf("Expected a notice for a non-group target, got %q", m.notice.text)
+	}
+
+	// Enter jumps to the target group via CurrentIdx
+	press(tea.Key{Text: "k", Code: 'k'})
+	press(tea.Key{Code: tea.KeyEnter})
+	if m.page != pageProxies || m.Groups[m.CurrentIdx] != "Proxy Group B" {
+		t.Errorf("Expected the proxies page on Proxy Group B, got page %d group %q", m.page, m.Groups[m.CurrentIdx])
+	}
+	if proxy := m.Proxies["Proxy Group B"]; proxy.All[m.Cursor] != proxy.Now {
+		t.Errorf("Expected the cursor on the active proxy")
+	}
+}
+
+func TestRuleToUnlistedGroup(t *testing.T) {
+	proxies := clash.MockProxies()
+	proxies["Backup"] = clash.Proxy{Name: "Backup", Type: "Fallback", Now: "Proxy-1", All: []string{"Proxy-1", "Proxy-2"}}
+	backend := clash.NewMemoryBackend(proxies)
+	backend.SetRules([]clash.Rule{{Type: "Match", Proxy: "Backup"}})
+	m := InitialModel(backend, Options{})
+	newModel, cmd := m.Update(reloadMsg{})
+	newModel, _ = newModel.Update(cmd())
+	m = newModel.(Model)
+
+	newModel, cmd = m.Update(tea.KeyPressMsg(tea.Key{Text: "4", Code: '4'}))
+	newModel, _ = newModel.Update(cmd())
+	newModel, _ = newModel.Update(tea.KeyPressMsg(tea.Key{Code: tea.KeyEnter}))
+	m = newModel.(Model)
+	if m.page != pageRules || !m.notice.err || !strings.Contains(m.notice.text, "Backup is a Fallback group") {
+		t.Errorf("Expected a notice naming the group type, got %q", m.notice.text)
+	}
+}
+
+func TestProvidersPage(t *testing.T) {
+	backend := clash.NewMemoryBackend(clash.MockProxies())
+	providers := clash.MockProxyProviders(time.Now())
+	providers["default"] = clash.ProxyProvider{Name: "default", VehicleType: "Compatible"}
+	backend.SetProxyProviders(providers)
+	m := InitialModel(backend, Options{})
+	newModel, cmd := m.Update(reloadMsg{})
+	newModel, _ = newModel.Update(cmd())
+	m = newModel.(Model)
+	press := func(key tea.Key) tea.Cmd {
+		newModel, cmd := m.Update(tea.KeyPressMsg(key))
+		m = newModel.(Model)
+		return cmd
+	}
+
+	cmd = press(tea.Key{Text: "5", Code: '5'})
+	if m.page != pageProviders || cmd == nil {
+		t.Fatalf("Expected 5 to open the Providers page and load the providers")
+	}
+	newModel, _ = m.Update(cmd())
+	m = newModel.(Model)
+	if len(m.providers.list) != 2 || m.providers.list[0].Name != "Local" {
+		t.Fatalf("Expected Local and Subscription without the built-in provider, got %+v", m.providers.list)
+	}
+	out := m.View().Content
+	for _, want := range []string{"Local", "File", "HTTP", "updated 1d ago", "↑3.0 GB ↓41.0 GB / 200.0 GB", "expires "} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in output:\n%s", want, out)
+		}
+	}
+
+	// Health check the subscription, then reload to see the results
+	press(tea.Key{Text: "j", Code: 'j'})
+	cmd = press(tea.Key{Text: "t", Code: 't'})
+	if m.providers.busy["Subscription"] != "healthcheck" || !strings.Contains(m.View().Content, "(healthcheck...)") {
+		t.Fatalf("Expected the health check to show as running")
+	}
+	if again := press(tea.Key{Text: "t", Code: 't'}); again != nil {
+		t.Errorf("Expected no second health check while one is running")
+	}
+	newModel, cmd = m.Update(cmd())
+	m = newModel.(Model)
+	if len(m.providers.busy) != 0 || m.notice.text != "Health check of Subscription done" {
+		t.Errorf("Expected a done notice, got %q", m.notice.text)
+	}
+	batch, ok := cmd().(tea.BatchMsg)
+	if !ok || len(batch) != 2 {
+		t.Fatalf("Expected notice and reload commands, got %#v", batch)
+	}
+	newModel, _ = m.Update(batch[1]())
+	m = newModel.(Model)
+	if out := m.View().Content; !strings.Contains(out, "7 proxies, 7 alive") {
+		t.Errorf("Expected the alive count after the health check:\n%s", out)
+	}
+
+	// Update moves updatedAt
+	cmd = press(tea.Key{Text: "u", Code: 'u'})
+	newModel, cmd = m.Update(cmd())
+	m = newModel.(Model)
+	newModel, _ = m.Update(cmd().(tea.BatchMsg)[1]())
+	m = newModel.(Model)
+	if m.notice.text != "Updated Subscription" || time.Since(m.providers.list[1].UpdatedAt) > time.Minute {
+		t.Errorf("Expected Subscription updated, got %q %v", m.notice.text, m.providers.list[1].UpdatedAt)
+	}
+
+	// Errors go to a notice
+	newModel, _ = m.Update(providerActionMsg{name: "gone", action: "update", err: &clash.ProviderNotFoundError{Provider: "gone"}})
+	m = newModel.(Model)
+	if !m.notice.err || !strings.Contains(m.notice.text, "Updating gone failed") {
+		t.Errorf("Expected an error notice, got %q", m.notice.text)
+	}
+}
+
+func TestRuleProvidersPage(t *testing.T) {
+	backend := clash.NewMemoryBackend(clash.MockProxies())
+	backend.SetRuleProviders(clash.MockRuleProviders(time.Now()))
+	m := InitialModel(backend, Options{})
+	newModel, cmd := m.Update(reloadMsg{})
+	newModel, _ = newModel.Update(cmd())
+	m = newModel.(Model)
+	press := func(key tea.Key) tea.Cmd {
+		newModel, cmd := m.Update(tea.KeyPressMsg(key))
+		m = newModel.(Model)
+		return cmd
+	}
+
+	cmd = press(tea.Key{Text: "6", Code: '6'})
+	if m.page != pageRuleProviders || cmd == nil {
+		t.Fatalf("Expected 6 to open the Rule Sets page and load the providers")
+	}
+	newModel, _ = m.Update(cmd())
+	m = newModel.(Model)
+	if len(m.ruleProviders.list) != 3 || m.ruleProviders.list[0].Name != "ads" {
+		t.Fatalf("Expected ads, lan and streaming, got %+v", m.ruleProviders.list)
+	}
+	out := m.View().Content
+	for _, want := range []string{"streaming", "MrsRule", "IPCIDR", "48210 rules", "updated 9d ago"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in output:\n%s", want, out)
+		}
+	}
+
+	// Update one
+	cmd = press(tea.Key{Text: "u", Code: 'u'})
+	if !strings.Contains(m.View().Content, "(updating...)") {
+		t.Errorf("Expected ads to show as updating")
+	}
+	newModel, _ = m.Update(cmd())
+	m = newModel.(Model)
+	if m.notice.text != "Updated ads" {
+		t.Errorf("Expected a notice for ads, got %q", m.notice.text)
+	}
+
+	// Update all, with lan failing
+	cmd = press(tea.Key{Text: "U", Code: 'U'})
+	batch, ok := cmd().(tea.BatchMsg)
+	if !ok || len(batch) != 3 {
+		t.Fatalf("Expected three updates, got %#v", batch)
+	}
+	for _, c := range batch {
+		msg := c().(ruleProviderUpdatedMsg)
+		if msg.name == "lan" {
+			msg.err = errors.New("file missing")
+		}
+		newModel, cmd = m.Update(msg)
+		m = newModel.(Model)
+	}
+	if cmd == nil || m.notice.text != "Updated 2/3 rule providers" || !m.notice.err {
+		t.Errorf("Expected a summary notice after the last update, got %q", m.notice.text)
+	}
+	out = m.View().Content
+	if !strings.Contains(out, "✖ file missing") || strings.Count(out, "✔") != 2 {
+		t.Errorf("Expected per-provider results:\n%s", out)
+	}
+}
+
+func TestRuleProvidersUnsupported(t *testing.T) {
+	m := InitialModel(clash.NewMemoryBackend(clash.MockProxies()), Options{})
+	m.Loading = false
+	m.Version = &clash.Version{Version: "v1.18.0"}
+	newModel, cmd := m.Update(tea.KeyPressMsg(tea.Key{Text: "6", Code: '6'}))
+	m = newModel.(Model)
+	if cmd != nil {
+		t.Errorf("Expected no request to a core without rule providers")
+	}
+	if out := m.View().Content; !strings.Contains(out, "need Clash Premium or Mihomo") {
+		t.Errorf("Expected an unsupported message:\n%s", out)
+	}
+}
+
+func TestModeSwitching(t *testing.T) {
+	proxies := clash.MockProxies()
+	proxies["GLOBAL"] = clash.Proxy{Name: "GLOBAL", Type: "Selector", Now: "Proxy Group B", All: []string{"Proxy Group A", "Proxy Group B"}}
+	backend := clash.NewMemoryBackend(proxies)
+	m := InitialModel(backend, Options{})
+	m.CurrentIdx = 2 // "Proxy Group B" once loaded
+
+	// The mode arrives before the proxies, focusing waits for them
+	newModel, _ := m.Update(configCmd(backend)())
+	m = newModel.(Model)
+	if m.mode != clash.ModeRule || !strings.Contains(m.tabsLine(), "mode rule") {
+		t.Errorf("Expected rule mode in the header, got %q", m.tabsLine())
+	}
+	newModel, cmd := m.Update(reloadMsg{})
+	newModel, _ = newModel.Update(cmd())
+	m = newModel.(Model)
+	if m.Groups[m.CurrentIdx] != "Proxy Group B" {
+		t.Fatalf("Expected Proxy Group B to stay focused in rule mode, got %q", m.Groups[m.CurrentIdx])
+	}
+
+	// m cycles rule -> global and focuses GLOBAL on its active entry
+	newModel, cmd = m.Update(tea.KeyPressMsg(tea.Key{Text: "m", Code: 'm'}))
+	newModel, _ = newModel.Update(cmd())
+	m = newModel.(Model)
+	if cfg, _ := backend.GetConfigContext(context.Background()); cfg.Mode != clash.ModeGlobal {
+		t.Errorf("Expected the core in global mode, got %q", cfg.Mode)
+	}
+	if m.notice.text != "Mode: global" || !strings.Contains(m.tabsLine(), "mode global") {
+		t.Errorf("Expected a global mode notice and header, got %q", m.notice.text)
+	}
+	if m.Groups[m.CurrentIdx] != "GLOBAL" || m.lastCursorProxy != "Proxy Group B" {
+		t.Errorf("Expected GLOBAL focused on Proxy Group B, got %q at %q", m.Groups[m.CurrentIdx], m.lastCursorProxy)
+	}
+
+	// Staying in global mode does not steal the focus again
+	m.selectGroup(0)
+	newModel, _ = m.Update(configCmd(backend)())
+	m = newModel.(Model)
+	if m.CurrentIdx != 0 {
+		t.Errorf("Expected the focus kept while already in global mode")
+	}
+
+	// global -> direct; failures go to a notice
+	newModel, cmd = m.Update(tea.KeyPressMsg(tea.Key{Text: "m", Code: 'm'}))
+	if msg := cmd().(modeChangedMsg); msg.mode != clash.ModeDirect {
+		t.Errorf("Expected direct after global, got %q", msg.mode)
+	}
+	newModel, _ = newModel.Update(modeChangedMsg{mode: clash.ModeDirect, err: errors.New("boom")})
+	m = newModel.(Model)
+	if !m.notice.err || m.mode != clash.ModeGlobal {
+		t.Errorf("Expected an error notice and the mode unchanged, got %q %q", m.notice.text, m.mode)
+	}
+}
+
+func TestConfigPage(t *testing.T) {
+	backend := clash.NewMemoryBackend(clash.MockProxies())
+	m := InitialModel(backend, Options{})
+	newModel, cmd := m.Update(reloadMsg{})
+	newModel, _ = newModel.Update(cmd())
+	m = newModel.(Model)
+	press := func(key tea.Key) tea.Cmd {
+		newModel, cmd := m.Update(tea.KeyPressMsg(key))
+		m = newModel.(Model)
+		return cmd
+	}
+	typeText := func(s string) {
+		for _, r := range s {
+			press(tea.Key{Text: string(r), Code: r})
+		}
+	}
+
+	cmd = press(tea.Key{Text: "7", Code: '7'})
+	if m.page != pageConfig || cmd == nil {
+		t.Fatalf("Expected 7 to open the Config page and load the config")
+	}
+	newModel, _ = m.Update(cmd())
+	m = newModel.(Model)
+	out := m.View().Content
+	for _, want := range []string{"allow-lan    false", "log-level    info", "tun.enable   false", "mixed-port   7890"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in output:\n%s", want, out)
+		}
+	}
+
+	// Toggle allow-lan, cycle log-level, toggle ipv6 and revert it
+	press(tea.Key{Code: tea.KeyEnter})
+	press(tea.Key{Text: "j", Code: 'j'})
+	press(tea.Key{Code: tea.KeySpace, Text: " "})
+	press(tea.Key{Text: "j", Code: 'j'})
+	press(tea.Key{Code: tea.KeyEnter})
+	press(tea.Key{Text: "x", Code: 'x'})
+	if len(m.config.pending) != 2 || m.config.pending["allow-lan"] != "true" || m.config.pending["log-level"] != "warning" {
+		t.Fatalf("Expected allow-lan and log-level pending, got %v", m.config.pending)
+	}
+
+	// An out of range port keeps the editor open
+	press(tea.Key{Text: "j", Code: 'j'})
+	press(tea.Key{Text: "j", Code: 'j'})
+	press(tea.Key{Code: tea.KeyEnter})
+	typeText("0")
+	press(tea.Key{Code: tea.KeyEnter})
+	if !m.config.edit.active || !strings.Contains(m.View().Content, "✖ must be 0-65535") {
+		t.Fatalf("Expected 78900 to be rejected:\n%s", m.View().Content)
+	}
+	for range 5 {
+		press(tea.Key{Code: tea.KeyBackspace})
+	}
+	typeText("7891")
+	press(tea.Key{Code: tea.KeyEnter})
+	if m.config.edit.active || m.config.pending["mixed-port"] != "7891" {
+		t.Fatalf("Expected mixed-port 7891 pending, got %v", m.config.pending)
+	}
+
+	out = m.View().Content
+	for _, want := range []string{"Pending changes", "- allow-lan: false", "+ allow-lan: true", "- mixed-port: 7890", "+ mixed-port: 7891"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in the diff:\n%s", want, out)
+		}
+	}
+
+	// s asks first, y sends one PATCH
+	if cmd := press(tea.Key{Text: "s", Code: 's'}); cmd != nil || m.confirm == nil || m.confirm.prompt != "Apply 3 changes?" {
+		t.Fatalf("Expected a confirmation prompt, got %+v", m.confirm)
+	}
+	cmd = press(tea.Key{Text: "y", Code: 'y'})
+	newModel, cmd = m.Update(cmd())
+	m = newModel.(Model)
+	if m.notice.text != "Applied 3 changes" || len(m.config.pending) != 0 {
+		t.Errorf("Expected the changes applied, got %q %v", m.notice.text, m.config.pending)
+	}
+	cfg, _ := backend.GetConfigContext(context.Background())
+	if !cfg.AllowLan || cfg.LogLevel != clash.LogWarning || cfg.MixedPort != 7891 || !cfg.IPv6 {
+		t.Errorf("Unexpected config after apply %+v", cfg)
+	}
+	newModel, _ = m.Update(cmd().(tea.BatchMsg)[1]())
+	m = newModel.(Model)
+	if !strings.Contains(m.View().Content, "mixed-port   7891") {
+		t.Errorf("Expected the reloaded config:\n%s", m.View().Content)
+	}
+}
+
+func TestReloadConfigAndRestart(t *testing.T) {
+	backend := clash.NewMemoryBackend(clash.MockProxies())
+	m := InitialModel(backend, Options{})
+	newModel, cmd := m.Update(reloadMsg{})
+	newModel, _ = newModel.Update(cmd())
+	m = newModel.(Model)
+	press := func(key tea.Key) tea.Cmd {
+		newModel, cmd := m.Update(tea.KeyPressMsg(key))
+		m = newModel.(Model)
+		return cmd
+	}
+
+	// Put the cursor on Auto-4 of Proxy Group B
+	press(tea.Key{Text: "l", Code: 'l'})
+	press(tea.Key{Text: "j", Code: 'j'})
+	press(tea.Key{Text: "j", Code: 'j'})
+	if m.lastCursorProxy != "Auto-4" {
+		t.Fatalf("Expected the cursor on Auto-4, got %q", m.lastCursorProxy)
+	}
+	mode := clash.ModeGlobal
+	backend.PatchConfigContext(context.Background(), clash.ConfigPatch{Mode: &mode})
+
+	// R asks first, then waits for the core
+	if cmd := press(tea.Key{Text: "R", Code: 'R'}); cmd != nil || m.confirm == nil {
+		t.Fatalf("Expected a confirmation prompt before reloading")
+	}
+	cmd = press(tea.Key{Text: "y", Code: 'y'})
+	newModel, cmd = m.Update(cmd())
+	m = newModel.(Model)
+	if m.waitingCore != "reload" || !strings.Contains(m.View().Content, "Waiting for the core after the config reload") {
+		t.Fatalf("Expected the waiting line:\n%s", m.View().Content)
+	}
+	if cfg, _ := backend.GetConfigContext(context.Background()); cfg.Mode != clash.ModeRule {
+		t.Errorf("Expected the reload to drop the runtime mode, got %q", cfg.Mode)
+	}
+	newModel, _ = m.Update(cmd())
+	m = newModel.(Model)
+	if m.waitingCore != "" || m.notice.text != "Config reloaded" {
+		t.Errorf("Expected the wait over with a notice, got %q", m.notice.text)
+	}
+	if m.Groups[m.CurrentIdx] != "Proxy Group B" || m.lastCursorProxy != "Auto-4" {
+		t.Errorf("Expected Proxy Group B on Auto-4, got %q on %q", m.Groups[m.CurrentIdx], m.lastCursorProxy)
+	}
+
+	// After a restart the group is found by name even if the list changed
+	press(tea.Key{Code: 'r', Mod: tea.ModCtrl})
+	if m.confirm == nil || !strings.Contains(m.confirm.prompt, "Restart the core?") {
+		t.Fatalf("Expected a restart prompt, got %+v", m.confirm)
+	}
+	cmd = press(tea.Key{Text: "y", Code: 'y'})
+	if msg := cmd().(coreActionMsg); msg.action != "restart" || msg.err != nil {
+		t.Fatalf("Unexpected restart result %+v", msg)
+	}
+	newModel, _ = m.Update(coreActionMsg{action: "restart"})
+	m = newModel.(Model)
+	proxies := clash.MockProxies()
+	proxies["A New Group"] = clash.Proxy{Name: "A New Group", Type: "Selector", Now: "Proxy-1", All: []string{"Proxy-1"}}
+	groups := []string{"A New Group", "Proxy Group A", "Proxy Group B", "Proxy Group C"}
+	newModel, _ = m.Update(coreBackMsg{loaded: proxiesLoadedMsg{proxies: proxies, groups: groups}})
+	m = newModel.(Model)
+	if m.Groups[m.CurrentIdx] != "Proxy Group B" || m.lastCursorProxy != "Auto-4" || m.notice.text != "Core restarted" {
+		t.Errorf("Expected Proxy Group B on Auto-4 after the restart, got %q on %q", m.Groups[m.CurrentIdx], m.lastCursorProxy)
+	}
+
+	// A core that never comes back goes through the usual reconnect loop
+	newModel, _ = m.Update(coreActionMsg{action: "restart"})
+	newModel, cmd = newModel.Update(coreBackMsg{err: &clash.UnreachableError{Address: "127.0.0.1:9090", Err: syscall.ECONNREFUSED}})
+	m = newModel.(Model)
+	if m.waitingCore != "" || m.Conn != Reconnecting || cmd == nil {
+		t.Errorf("Expected reconnecting, got %v", m.Conn)
+	}
+
+	// Plain Clash has no /restart
+	m.Version = &clash.Version{Version: "v1.18.0"}
+	press(tea.Key{Code: 'r', Mod: tea.ModCtrl})
+	if m.confirm != nil || !m.notice.err {
+		t.Errorf("Expected restart refused without a prompt, got %q", m.notice.text)
+	}
+}
+
+func TestFlushCache(t *testing.T) {
+	m := InitialModel(clash.NewMemoryBackend(clash.MockProxies()), Options{})
+	m.Loading = false
+	m.page = pageLogs
+	press := func(key tea.Key) tea.Cmd {
+		newModel, cmd := m.Update(tea.KeyPressMsg(key))
+		m = newModel.(Model)
+		return cmd
+	}
+
+	cmd := press(tea.Key{Text: "F", Code: 'F'})
+	newModel, _ := m.Update(cmd())
+	m = newModel.(Model)
+	if m.notice.text != "Flushed the fake-IP cache" || m.notice.err {
+		t.Errorf("Expected a fake-IP notice, got %q", m.notice.text)
+	}
+	cmd = press(tea.Key{Text: "D", Code: 'D'})
+	newModel, _ = m.Update(cmd())
+	m = newModel.(Model)
+	if m.notice.text != "Flushed the DNS cache" {
+		t.Errorf("Expected a DNS notice, got %q", m.notice.text)
+	}
+
+	// Failures stay in a notice, the page remains on screen
+	newModel, _ = m.Update(cacheFlushedMsg{cache: "DNS", err: &clash.StatusError{StatusCode: 500, Message: "boom"}})
+	m = newModel.(Model)
+	if m.Err != nil || !m.notice.err || !strings.Contains(m.notice.text, "Flushing the DNS cache failed") {
+		t.Errorf("Expected an error notice without the error screen, got %q (%v)", m.notice.text, m.Err)
+	}
+	if !strings.Contains(m.View().Content, "✖ Flushing the DNS cache failed") {
+		t.Errorf("Expected the notice in the header:\n%s", m.View().Content)
+	}
+
+	m.Version = &clash.Version{Version: "v1.18.0"}
+	if cmd := press(tea.Key{Text: "F", Code: 'F'}); cmd == nil || !m.notice.err || !strings.Contains(m.notice.text, "Cache flushing: not supported by Clash v1.18.0") {
+		t.Errorf("Expected an unsupported notice, got %q", m.notice.text)
+	}
+}
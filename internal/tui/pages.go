@@ -0,0 +1,135 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+)
+
+// page is a top-level screen, switched with Tab/Shift+Tab or the digit keys.
+type page int
+
+const (
+	pageProxies page = iota
+	pageConnections
+	pageLogs
+	pageRules
+	pageProviders
+	pageRuleProviders
+	pageConfig
+	pageCount
+)
+
+var pageNames = [pageCount]string{
+	pageProxies:       "Proxies",
+	pageConnections:   "Connections",
+	pageLogs:          "Logs",
+	pageRules:         "Rules",
+	pageProviders:     "Providers",
+	pageRuleProviders: "Rule Sets",
+	pageConfig:        "Config",
+}
+
+var (
+	activeTabStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("231")).Background(lipgloss.Color("63")).Bold(true)
+	inactiveTabStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+)
+
+// handleGlobalKey handles keys that work the same on every page. ok is
+// false when the key is left to the current page.
+func (m Model) handleGlobalKey(msg tea.KeyPressMsg) (Model, tea.Cmd, bool) {
+	key := msg.Key()
+	switch {
+	case key.Text == "q" && key.Mod == 0:
+		return m, tea.Quit, true
+
+	case key.Text == "m" && key.Mod == 0:
+		return m, setModeCmd(m.Backend, m.nextMode()), true
+
+	case key.Text == "R":
+		next, cmd := m.confirmReloadConfig()
+		return next, cmd, true
+
+	case key.Code == 'r' && key.Mod == tea.ModCtrl:
+		next, cmd := m.confirmRestartCore()
+		return next, cmd, true
+
+	case key.Text == "F":
+		next, cmd := m.flushCache(flushFakeIPCacheCmd)
+		return next, cmd, true
+
+	case key.Text == "D":
+		next, cmd := m.flushCache(flushDNSCacheCmd)
+		return next, cmd, true
+
+	case key.Code == tea.KeyTab && key.Mod == 0:
+		next, cmd := m.switchPage((m.page + 1) % pageCount)
+		return next, cmd, true
+
+	case key.Code == tea.KeyTab && key.Mod == tea.ModShift:
+		next, cmd := m.switchPage((m.page + pageCount - 1) % pageCount)
+		return next, cmd, true
+
+	case len(key.Text) == 1 && key.Text >= "1" && key.Text <= "9" && key.Mod == 0:
+		if p := page(key.Text[0] - '1'); p < pageCount {
+			next, cmd := m.switchPage(p)
+			return next, cmd, true
+		}
+	}
+	return m, nil, false
+}
+
+// switchPage shows another page and starts whatever it needs to stay fresh.
+func (m Model) switchPage(p page) (Model, tea.Cmd) {
+	if p == m.page {
+		return m, nil
+	}
+	m.page = p
+	switch p {
+	case pageConnections:
+		return m.startConnectionsRefresh()
+	case pageLogs:
+		// The stream keeps running in the background once started
+		if !m.logs.started {
+			return m.startLogs()
+		}
+	case pageRules:
+		return m, fetchRulesCmd(m.Backend)
+	case pageProviders:
+		return m, fetchProvidersCmd(m.Backend)
+	case pageRuleProviders:
+		if m.caps().RuleProviders {
+			return m, fetchRuleProvidersCmd(m.Backend)
+		}
+	case pageConfig:
+		return m, fetchConfigCmd(m.Backend)
+	}
+	return m, nil
+}
+
+// tabsLine renders the page tabs followed by the core version, its mode
+// and its memory use.
+func (m Model) tabsLine() string {
+	var tabs []string
+	for i, name := range pageNames {
+		label := fmt.Sprintf(" %d %s ", i+1, name)
+		if page(i) == m.page {
+			tabs = append(tabs, activeTabStyle.Render(label))
+		} else {
+			tabs = append(tabs, inactiveTabStyle.Render(label))
+		}
+	}
+	line := strings.Join(tabs, "")
+	if m.Version != nil {
+		line += separatorStyle.Render(" │ ") + headerStyle.Render(m.Version.String())
+	}
+	if mode := m.modeIndicator(); mode != "" {
+		line += separatorStyle.Render(" │ ") + mode
+	}
+	if mem := m.memoryIndicator(); mem != "" {
+		line += separatorStyle.Render(" │ ") + mem
+	}
+	return line
+}
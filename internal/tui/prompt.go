@@ -0,0 +1,86 @@
+package tui
+
+import (
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+)
+
+const noticeDuration = 5 * time.Second
+
+var (
+	confirmStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("231")).Background(lipgloss.Color("166")).Bold(true)
+	noticeStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+	noticeErrorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+)
+
+// confirmation asks y/N before running a destructive command.
+type confirmation struct {
+	prompt string
+	onYes  tea.Cmd
+}
+
+// notice is a short-lived result message shown above the page, so failures
+// of secondary actions don't take over the whole view like m.Err.
+type notice struct {
+	text string
+	err  bool
+	seq  int
+}
+
+type noticeExpiredMsg struct {
+	seq int
+}
+
+// askConfirm shows a y/N prompt; onYes runs only when the user agrees.
+func (m *Model) askConfirm(prompt string, onYes tea.Cmd) {
+	m.confirm = &confirmation{prompt: prompt, onYes: onYes}
+	m.relayout()
+}
+
+// handleConfirmKey answers the open prompt: y runs the command, anything
+// else dismisses it.
+func (m Model) handleConfirmKey(msg tea.KeyPressMsg) (Model, tea.Cmd) {
+	c := m.confirm
+	m.confirm = nil
+	m.relayout()
+	if key := msg.Key(); key.Text == "y" || key.Text == "Y" {
+		return m, c.onYes
+	}
+	return m, nil
+}
+
+// setNotice shows text until it expires or is replaced.
+func (m *Model) setNotice(text string, isErr bool) tea.Cmd {
+	seq := m.notice.seq + 1
+	m.notice = notice{text: text, err: isErr, seq: seq}
+	m.relayout()
+	return tea.Tick(noticeDuration, func(time.Time) tea.Msg {
+		return noticeExpiredMsg{seq: seq}
+	})
+}
+
+func (m Model) handleNoticeExpired(msg noticeExpiredMsg) (Model, tea.Cmd) {
+	if msg.seq == m.notice.seq {
+		m.notice.text = ""
+		m.relayout()
+	}
+	return m, nil
+}
+
+// promptLines renders the open confirmation and the current notice.
+func (m Model) promptLines() []string {
+	var lines []string
+	if m.confirm != nil {
+		lines = append(lines, confirmStyle.Render(" "+m.confirm.prompt+" [y/N] "))
+	}
+	if m.notice.text != "" {
+		if m.notice.err {
+			lines = append(lines, noticeErrorStyle.Render("✖ "+m.notice.text))
+		} else {
+			lines = append(lines, noticeStyle.Render("✔ "+m.notice.text))
+		}
+	}
+	return lines
+}
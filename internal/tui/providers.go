@@ -0,0 +1,261 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/wallacegibbon/proxy-controller-tui/internal/clash"
+)
+
+var (
+	providerVehicleStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("147"))
+	providerBusyStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+)
+
+// providersState is the state of the Providers page.
+type providersState struct {
+	list   []clash.ProxyProvider // sorted by name, without the built-in one
+	cursor int
+	offset int
+	loaded bool
+	err    error
+	busy   map[string]string // provider name to the running action
+}
+
+type providersMsg struct {
+	resp *clash.ProxyProvidersResponse
+	err  error
+}
+
+// providerActionMsg reports the end of an update or health check.
+type providerActionMsg struct {
+	name   string
+	action string // "update" or "healthcheck"
+	err    error
+}
+
+func fetchProvidersCmd(backend clash.Backend) tea.Cmd {
+	return func() tea.Msg {
+		resp, err := backend.GetProxyProvidersContext(context.Background())
+		return providersMsg{resp: resp, err: err}
+	}
+}
+
+func updateProviderCmd(backend clash.Backend, name string) tea.Cmd {
+	return func() tea.Msg {
+		err := backend.UpdateProxyProviderContext(context.Background(), name)
+		return providerActionMsg{name: name, action: "update", err: err}
+	}
+}
+
+func healthCheckProviderCmd(backend clash.Backend, name string) tea.Cmd {
+	return func() tea.Msg {
+		err := backend.HealthCheckProxyProviderContext(context.Background(), name)
+		return providerActionMsg{name: name, action: "healthcheck", err: err}
+	}
+}
+
+func (m Model) handleProvidersMsg(msg providersMsg) (Model, tea.Cmd) {
+	if msg.err != nil {
+		if errors.Is(msg.err, context.Canceled) {
+			return m, nil
+		}
+		m.providers.err = msg.err
+		return m, nil
+	}
+	m.providers.err = nil
+	m.providers.loaded = true
+
+	list := make([]clash.ProxyProvider, 0, len(msg.resp.Providers))
+	for _, p := range msg.resp.Providers {
+		// "default" holds every proxy of the config file, not a provider
+		if p.VehicleType == "Compatible" {
+			continue
+		}
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	m.providers.list = list
+	m.setProvidersCursor(m.providers.cursor)
+	return m, nil
+}
+
+func (m Model) handleProviderAction(msg providerActionMsg) (Model, tea.Cmd) {
+	delete(m.providers.busy, msg.name)
+
+	var notice tea.Cmd
+	switch {
+	case msg.err != nil && msg.action == "update":
+		notice = m.setNotice(fmt.Sprintf("Updating %s failed: %v", msg.name, msg.err), true)
+	case msg.err != nil:
+		notice = m.setNotice(fmt.Sprintf("Health check of %s failed: %v", msg.name, msg.err), true)
+	case msg.action == "update":
+		notice = m.setNotice("Updated "+msg.name, false)
+	default:
+		notice = m.setNotice("Health check of "+msg.name+" done", false)
+	}
+	return m, tea.Batch(notice, fetchProvidersCmd(m.Backend))
+}
+
+func (m *Model) setProvidersCursor(cursor int) {
+	if cursor >= len(m.providers.list) {
+		cursor = len(m.providers.list) - 1
+	}
+	if cursor < 0 {
+		cursor = 0
+	}
+	m.providers.cursor = cursor
+
+	rows := m.providerRows()
+	if cursor < m.providers.offset {
+		m.providers.offset = cursor
+	} else if cursor >= m.providers.offset+rows {
+		m.providers.offset = cursor - rows + 1
+	}
+	if maxOffset := len(m.providers.list) - rows; m.providers.offset > maxOffset {
+		m.providers.offset = maxOffset
+	}
+	if m.providers.offset < 0 {
+		m.providers.offset = 0
+	}
+}
+
+// providerRows is the number of providers shown below the summary line.
+func (m Model) providerRows() int {
+	n := m.Height - len(m.headerLines()) - 1
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// startProviderAction runs an update or health check of the provider under
+// the cursor unless one is already running for it.
+func (m Model) startProviderAction(action string) (Model, tea.Cmd) {
+	if m.providers.cursor >= len(m.providers.list) {
+		return m, nil
+	}
+	name := m.providers.list[m.providers.cursor].Name
+	if _, ok := m.providers.busy[name]; ok {
+		return m, nil
+	}
+	if m.providers.busy == nil {
+		m.providers.busy = make(map[string]string)
+	}
+	m.providers.busy[name] = action
+	if action == "update" {
+		return m, updateProviderCmd(m.Backend, name)
+	}
+	return m, healthCheckProviderCmd(m.Backend, name)
+}
+
+func (m Model) updateProviders(msg tea.KeyPressMsg) (Model, tea.Cmd) {
+	switch key := msg.Key(); {
+	case key.Code == tea.KeyUp || (key.Text == "k" && key.Mod == 0):
+		m.setProvidersCursor(m.providers.cursor - 1)
+	case key.Code == tea.KeyDown || (key.Text == "j" && key.Mod == 0):
+		m.setProvidersCursor(m.providers.cursor + 1)
+	case key.Code == tea.KeyHome || (key.Text == "g" && key.Mod == 0):
+		m.setProvidersCursor(0)
+	case key.Code == tea.KeyEnd || key.Text == "G":
+		m.setProvidersCursor(len(m.providers.list) - 1)
+	case key.Text == "r" && key.Mod == 0:
+		return m, fetchProvidersCmd(m.Backend)
+	case key.Text == "u" && key.Mod == 0:
+		return m.startProviderAction("update")
+	case key.Text == "t" && key.Mod == 0:
+		if !m.caps().ProviderHealthcheck {
+			return m, m.unsupported("Provider health checks")
+		}
+		return m.startProviderAction("healthcheck")
+	}
+	return m, nil
+}
+
+func (m Model) viewProviders() string {
+	s := selectedGroupStyle.Render(fmt.Sprintf(" %d proxy providers ", len(m.providers.list))) + "\n"
+
+	switch {
+	case m.providers.err != nil:
+		return s + fixedIndicatorStyle.Render("  "+m.providers.err.Error()) + "\n"
+	case !m.providers.loaded:
+		return s + helpStyle.Render("  Loading providers...") + "\n"
+	case len(m.providers.list) == 0:
+		return s + helpStyle.Render("  No proxy providers") + "\n"
+	}
+
+	now := time.Now()
+	end := m.providers.offset + m.providerRows()
+	if end > len(m.providers.list) {
+		end = len(m.providers.list)
+	}
+	for i := m.providers.offset; i < end; i++ {
+		marker := "   "
+		if i == m.providers.cursor {
+			marker = cursorStyle.Render(">  ")
+		}
+		p := m.providers.list[i]
+		line := providerLine(p, now)
+		if action, ok := m.providers.busy[p.Name]; ok {
+			line += " " + providerBusyStyle.Render("("+action+"...)")
+		}
+		s += m.fit(marker+line) + "\n"
+	}
+	return s
+}
+
+// providerLine renders a provider as
+// "name  HTTP  12 proxies, 9 alive  updated 3h ago  ↑3.0 GB ↓41.0 GB / 200.0 GB, expires 2026-12-16".
+func providerLine(p clash.ProxyProvider, now time.Time) string {
+	line := fmt.Sprintf("%-16s ", p.Name) + providerVehicleStyle.Render(fmt.Sprintf("%-6s", p.VehicleType)) + " "
+
+	count := fmt.Sprintf("%d proxies", len(p.Proxies))
+	if checked, alive := providerAlive(p); checked > 0 {
+		count += fmt.Sprintf(", %d alive", alive)
+	}
+	line += count
+
+	if !p.UpdatedAt.IsZero() {
+		line += "  " + helpStyle.Render("updated "+formatAge(now.Sub(p.UpdatedAt))+" ago")
+	}
+
+	if info := p.SubscriptionInfo; info != nil {
+		var parts []string
+		used := fmt.Sprintf("↑%s ↓%s", formatBytes(info.Upload), formatBytes(info.Download))
+		if info.Total > 0 {
+			used += " / " + formatBytes(info.Total)
+		}
+		parts = append(parts, used)
+		if info.Expire > 0 {
+			expire := time.Unix(info.Expire, 0)
+			if expire.Before(now) {
+				parts = append(parts, fixedIndicatorStyle.Render("expired "+expire.Format(time.DateOnly)))
+			} else {
+				parts = append(parts, "expires "+expire.Format(time.DateOnly))
+			}
+		}
+		line += "  " + strings.Join(parts, ", ")
+	}
+	return line
+}
+
+// providerAlive counts the proxies with a health check result and those
+// that passed it.
+func providerAlive(p clash.ProxyProvider) (checked, alive int) {
+	for _, proxy := range p.Proxies {
+		if len(proxy.History) == 0 {
+			continue
+		}
+		checked++
+		if proxy.History[len(proxy.History)-1].Delay > 0 {
+			alive++
+		}
+	}
+	return checked, alive
+}
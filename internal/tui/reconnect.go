@@ -0,0 +1,118 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/wallacegibbon/proxy-controller-tui/internal/clash"
+)
+
+// ConnState tracks whether the controller is reachable.
+type ConnState int
+
+const (
+	Connected    ConnState = iota
+	Reconnecting           // retrying with growing delays
+	Offline                // gave up backing off, retrying at the longest delay
+)
+
+func (s ConnState) String() string {
+	switch s {
+	case Connected:
+		return "connected"
+	case Reconnecting:
+		return "reconnecting"
+	case Offline:
+		return "offline"
+	}
+	return "unknown"
+}
+
+const (
+	initialReconnectDelay = time.Second
+	maxReconnectDelay     = 30 * time.Second
+	maxReconnectAttempts  = 5 // attempts before the state turns Offline
+)
+
+var (
+	reconnectingStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Bold(true)
+	offlineStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Bold(true)
+)
+
+type reconnectMsg struct {
+	attempt int
+}
+
+// reconnectBackoff doubles the delay on every attempt, up to maxReconnectDelay.
+func reconnectBackoff(attempt int) time.Duration {
+	d := initialReconnectDelay
+	for i := 1; i < attempt && d < maxReconnectDelay; i++ {
+		d *= 2
+	}
+	if d > maxReconnectDelay {
+		d = maxReconnectDelay
+	}
+	return d
+}
+
+func reconnectCmd(attempt int, delay time.Duration) tea.Cmd {
+	return tea.Tick(delay, func(time.Time) tea.Msg {
+		return reconnectMsg{attempt: attempt}
+	})
+}
+
+// handleError routes a backend error. Losing the controller starts the
+// reconnect loop and keeps the last known proxies on screen; anything else
+// is shown on the error screen.
+func (m Model) handleError(err error) (Model, tea.Cmd) {
+	m.cancelRequest()
+	m.Loading = false
+
+	var unreachable *clash.UnreachableError
+	if !errors.As(err, &unreachable) {
+		m.Err = err
+		return m, nil
+	}
+
+	m.reconnectAttempt++
+	if m.reconnectAttempt > maxReconnectAttempts {
+		m.Conn = Offline
+	} else {
+		m.Conn = Reconnecting
+	}
+	m.reconnectDelay = reconnectBackoff(m.reconnectAttempt)
+	if len(m.Groups) == 0 {
+		// Nothing to keep showing
+		m.Err = err
+	}
+	return m, reconnectCmd(m.reconnectAttempt, m.reconnectDelay)
+}
+
+// handleReconnect probes the controller unless the tick is stale.
+func (m Model) handleReconnect(msg reconnectMsg) (Model, tea.Cmd) {
+	if m.Conn == Connected || msg.attempt != m.reconnectAttempt || m.Loading {
+		return m, nil
+	}
+	return m, LoadProxiesCmd(m.beginRequest(), m.Backend)
+}
+
+// markConnected resets the reconnect loop after a successful load.
+func (m *Model) markConnected() {
+	m.Conn = Connected
+	m.reconnectAttempt = 0
+	m.reconnectDelay = 0
+}
+
+// connBanner describes the connection state, empty while connected.
+func (m Model) connBanner() string {
+	switch m.Conn {
+	case Reconnecting:
+		return reconnectingStyle.Render(fmt.Sprintf("⟳ Controller unreachable, retrying in %s (attempt %d)", m.reconnectDelay, m.reconnectAttempt))
+	case Offline:
+		return offlineStyle.Render(fmt.Sprintf("✖ Offline, retrying every %s, [r] retry now", m.reconnectDelay))
+	}
+	return ""
+}
@@ -0,0 +1,249 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/wallacegibbon/proxy-controller-tui/internal/clash"
+)
+
+// ruleProvidersState is the state of the Rule Sets page.
+type ruleProvidersState struct {
+	list    []clash.RuleProvider // sorted by name
+	cursor  int
+	offset  int
+	loaded  bool
+	err     error
+	busy    map[string]bool
+	results map[string]error // outcome of the last update, nil when it worked
+
+	// batch holds the providers still updating for "update all", whose
+	// summary is posted once it is empty.
+	batch       map[string]bool
+	batchTotal  int
+	batchFailed int
+}
+
+type ruleProvidersMsg struct {
+	resp *clash.RuleProvidersResponse
+	err  error
+}
+
+type ruleProviderUpdatedMsg struct {
+	name string
+	err  error
+}
+
+func fetchRuleProvidersCmd(backend clash.Backend) tea.Cmd {
+	return func() tea.Msg {
+		resp, err := backend.GetRuleProvidersContext(context.Background())
+		return ruleProvidersMsg{resp: resp, err: err}
+	}
+}
+
+func updateRuleProviderCmd(backend clash.Backend, name string) tea.Cmd {
+	return func() tea.Msg {
+		err := backend.UpdateRuleProviderContext(context.Background(), name)
+		return ruleProviderUpdatedMsg{name: name, err: err}
+	}
+}
+
+func (m Model) handleRuleProvidersMsg(msg ruleProvidersMsg) (Model, tea.Cmd) {
+	if msg.err != nil {
+		if errors.Is(msg.err, context.Canceled) {
+			return m, nil
+		}
+		m.ruleProviders.err = msg.err
+		return m, nil
+	}
+	m.ruleProviders.err = nil
+	m.ruleProviders.loaded = true
+
+	list := make([]clash.RuleProvider, 0, len(msg.resp.Providers))
+	for _, p := range msg.resp.Providers {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	m.ruleProviders.list = list
+	m.setRuleProvidersCursor(m.ruleProviders.cursor)
+	return m, nil
+}
+
+func (m Model) handleRuleProviderUpdated(msg ruleProviderUpdatedMsg) (Model, tea.Cmd) {
+	rp := &m.ruleProviders
+	delete(rp.busy, msg.name)
+	if rp.results == nil {
+		rp.results = make(map[string]error)
+	}
+	rp.results[msg.name] = msg.err
+
+	if rp.batch[msg.name] {
+		delete(rp.batch, msg.name)
+		if msg.err != nil {
+			rp.batchFailed++
+		}
+		if len(rp.batch) > 0 {
+			return m, nil
+		}
+		text := fmt.Sprintf("Updated %d/%d rule providers", rp.batchTotal-rp.batchFailed, rp.batchTotal)
+		return m, tea.Batch(m.setNotice(text, rp.batchFailed > 0), fetchRuleProvidersCmd(m.Backend))
+	}
+
+	if msg.err != nil {
+		return m, tea.Batch(m.setNotice(fmt.Sprintf("Updating %s failed: %v", msg.name, msg.err), true), fetchRuleProvidersCmd(m.Backend))
+	}
+	return m, tea.Batch(m.setNotice("Updated "+msg.name, false), fetchRuleProvidersCmd(m.Backend))
+}
+
+// startRuleProviderUpdate marks a provider busy and returns the command
+// updating it, or nil when an update is already running.
+func (m *Model) startRuleProviderUpdate(name string) tea.Cmd {
+	if m.ruleProviders.busy[name] {
+		return nil
+	}
+	if m.ruleProviders.busy == nil {
+		m.ruleProviders.busy = make(map[string]bool)
+	}
+	m.ruleProviders.busy[name] = true
+	return updateRuleProviderCmd(m.Backend, name)
+}
+
+// updateAllRuleProviders updates every provider concurrently. Those already
+// updating are left out of the batch.
+func (m Model) updateAllRuleProviders() (Model, tea.Cmd) {
+	if len(m.ruleProviders.batch) > 0 {
+		return m, nil
+	}
+	batch := make(map[string]bool)
+	var cmds []tea.Cmd
+	for _, p := range m.ruleProviders.list {
+		if cmd := m.startRuleProviderUpdate(p.Name); cmd != nil {
+			batch[p.Name] = true
+			cmds = append(cmds, cmd)
+		}
+	}
+	if len(cmds) == 0 {
+		return m, nil
+	}
+	m.ruleProviders.batch = batch
+	m.ruleProviders.batchTotal = len(batch)
+	m.ruleProviders.batchFailed = 0
+	return m, tea.Batch(cmds...)
+}
+
+func (m *Model) setRuleProvidersCursor(cursor int) {
+	if cursor >= len(m.ruleProviders.list) {
+		cursor = len(m.ruleProviders.list) - 1
+	}
+	if cursor < 0 {
+		cursor = 0
+	}
+	m.ruleProviders.cursor = cursor
+
+	rows := m.ruleProviderRows()
+	if cursor < m.ruleProviders.offset {
+		m.ruleProviders.offset = cursor
+	} else if cursor >= m.ruleProviders.offset+rows {
+		m.ruleProviders.offset = cursor - rows + 1
+	}
+	if maxOffset := len(m.ruleProviders.list) - rows; m.ruleProviders.offset > maxOffset {
+		m.ruleProviders.offset = maxOffset
+	}
+	if m.ruleProviders.offset < 0 {
+		m.ruleProviders.offset = 0
+	}
+}
+
+// ruleProviderRows is the number of providers shown below the summary line.
+func (m Model) ruleProviderRows() int {
+	n := m.Height - len(m.headerLines()) - 1
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+func (m Model) updateRuleProviders(msg tea.KeyPressMsg) (Model, tea.Cmd) {
+	key := msg.Key()
+	if !m.caps().RuleProviders && ((key.Text == "r" || key.Text == "u") && key.Mod == 0 || key.Text == "U") {
+		return m, m.unsupported("Rule providers")
+	}
+	switch {
+	case key.Code == tea.KeyUp || (key.Text == "k" && key.Mod == 0):
+		m.setRuleProvidersCursor(m.ruleProviders.cursor - 1)
+	case key.Code == tea.KeyDown || (key.Text == "j" && key.Mod == 0):
+		m.setRuleProvidersCursor(m.ruleProviders.cursor + 1)
+	case key.Code == tea.KeyHome || (key.Text == "g" && key.Mod == 0):
+		m.setRuleProvidersCursor(0)
+	case key.Code == tea.KeyEnd || key.Text == "G":
+		m.setRuleProvidersCursor(len(m.ruleProviders.list) - 1)
+	case key.Text == "r" && key.Mod == 0:
+		return m, fetchRuleProvidersCmd(m.Backend)
+	case key.Text == "u" && key.Mod == 0:
+		if m.ruleProviders.cursor < len(m.ruleProviders.list) {
+			cmd := m.startRuleProviderUpdate(m.ruleProviders.list[m.ruleProviders.cursor].Name)
+			return m, cmd
+		}
+	case key.Text == "U":
+		return m.updateAllRuleProviders()
+	}
+	return m, nil
+}
+
+func (m Model) viewRuleProviders() string {
+	s := selectedGroupStyle.Render(fmt.Sprintf(" %d rule providers ", len(m.ruleProviders.list))) + "\n"
+
+	switch {
+	case !m.caps().RuleProviders:
+		return s + helpStyle.Render("  Rule providers need Clash Premium or Mihomo") + "\n"
+	case m.ruleProviders.err != nil:
+		return s + fixedIndicatorStyle.Render("  "+m.ruleProviders.err.Error()) + "\n"
+	case !m.ruleProviders.loaded:
+		return s + helpStyle.Render("  Loading rule providers...") + "\n"
+	case len(m.ruleProviders.list) == 0:
+		return s + helpStyle.Render("  No rule providers") + "\n"
+	}
+
+	now := time.Now()
+	end := m.ruleProviders.offset + m.ruleProviderRows()
+	if end > len(m.ruleProviders.list) {
+		end = len(m.ruleProviders.list)
+	}
+	for i := m.ruleProviders.offset; i < end; i++ {
+		marker := "   "
+		if i == m.ruleProviders.cursor {
+			marker = cursorStyle.Render(">  ")
+		}
+		p := m.ruleProviders.list[i]
+		line := ruleProviderLine(p, now)
+		if m.ruleProviders.busy[p.Name] {
+			line += " " + providerBusyStyle.Render("(updating...)")
+		} else if err, ok := m.ruleProviders.results[p.Name]; ok {
+			if err != nil {
+				line += " " + fixedIndicatorStyle.Render("✖ "+err.Error())
+			} else {
+				line += " " + fastDelayStyle.Render("✔")
+			}
+		}
+		s += m.fit(marker+line) + "\n"
+	}
+	return s
+}
+
+// ruleProviderLine renders a provider as
+// "name  Domain  MrsRule  HTTP  1532 rules  updated 5h ago".
+func ruleProviderLine(p clash.RuleProvider, now time.Time) string {
+	line := fmt.Sprintf("%-16s ", p.Name) +
+		ruleTypeStyle.Render(fmt.Sprintf("%-9s", p.Behavior)) + " " +
+		fmt.Sprintf("%-8s ", p.Format) +
+		providerVehicleStyle.Render(fmt.Sprintf("%-6s", p.VehicleType)) + " " +
+		fmt.Sprintf("%d rules", p.RuleCount)
+	if !p.UpdatedAt.IsZero() {
+		line += "  " + helpStyle.Render("updated "+formatAge(now.Sub(p.UpdatedAt))+" ago")
+	}
+	return line
+}
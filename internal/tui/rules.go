@@ -0,0 +1,218 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/wallacegibbon/proxy-controller-tui/internal/clash"
+)
+
+var (
+	ruleTypeStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("147"))
+	ruleTargetStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("86"))
+	ruleDisabledStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Strikethrough(true)
+)
+
+// rulesState is the state of the Rules page.
+type rulesState struct {
+	all    []clash.Rule
+	list   []clash.Rule // all, filtered
+	filter textInput
+	cursor int
+	offset int
+	loaded bool
+	err    error
+}
+
+type rulesMsg struct {
+	resp *clash.RulesResponse
+	err  error
+}
+
+func fetchRulesCmd(backend clash.Backend) tea.Cmd {
+	return func() tea.Msg {
+		resp, err := backend.GetRulesContext(context.Background())
+		return rulesMsg{resp: resp, err: err}
+	}
+}
+
+func (m Model) handleRulesMsg(msg rulesMsg) (Model, tea.Cmd) {
+	if msg.err != nil {
+		if errors.Is(msg.err, context.Canceled) {
+			return m, nil
+		}
+		m.rules.err = msg.err
+		return m, nil
+	}
+	m.rules.err = nil
+	m.rules.loaded = true
+	m.rules.all = msg.resp.Rules
+	m.applyRules()
+	return m, nil
+}
+
+// applyRules rebuilds the visible list from the filter. Each word of the
+// filter must match: "type:" and "proxy:" (or "group:") words only look at
+// that column, others at the type, payload and target.
+func (m *Model) applyRules() {
+	words := strings.Fields(strings.ToLower(m.rules.filter.value))
+	list := make([]clash.Rule, 0, len(m.rules.all))
+	for _, r := range m.rules.all {
+		if matchRule(r, words) {
+			list = append(list, r)
+		}
+	}
+	m.rules.list = list
+	m.setRulesCursor(m.rules.cursor)
+}
+
+func matchRule(r clash.Rule, words []string) bool {
+	typ, payload, proxy := strings.ToLower(r.Type), strings.ToLower(r.Payload), strings.ToLower(r.Proxy)
+	for _, w := range words {
+		field, value, scoped := strings.Cut(w, ":")
+		switch {
+		case scoped && field == "type":
+			if !strings.Contains(typ, value) {
+				return false
+			}
+		case scoped && (field == "proxy" || field == "group"):
+			if !strings.Contains(proxy, value) {
+				return false
+			}
+		default:
+			if !strings.Contains(typ, w) && !strings.Contains(payload, w) && !strings.Contains(proxy, w) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (m *Model) setRulesCursor(cursor int) {
+	if cursor >= len(m.rules.list) {
+		cursor = len(m.rules.list) - 1
+	}
+	if cursor < 0 {
+		cursor = 0
+	}
+	m.rules.cursor = cursor
+
+	rows := m.ruleRows()
+	if cursor < m.rules.offset {
+		m.rules.offset = cursor
+	} else if cursor >= m.rules.offset+rows {
+		m.rules.offset = cursor - rows + 1
+	}
+	if maxOffset := len(m.rules.list) - rows; m.rules.offset > maxOffset {
+		m.rules.offset = maxOffset
+	}
+	if m.rules.offset < 0 {
+		m.rules.offset = 0
+	}
+}
+
+// ruleRows is the number of rules shown below the summary and filter lines.
+func (m Model) ruleRows() int {
+	n := m.Height - len(m.headerLines()) - 1
+	if m.rules.filterShown() {
+		n--
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+func (s rulesState) filterShown() bool {
+	return s.filter.active || s.filter.value != ""
+}
+
+func (m Model) updateRules(msg tea.KeyPressMsg) (Model, tea.Cmd) {
+	switch key := msg.Key(); {
+	case key.Code == tea.KeyUp || (key.Text == "k" && key.Mod == 0):
+		m.setRulesCursor(m.rules.cursor - 1)
+	case key.Code == tea.KeyDown || (key.Text == "j" && key.Mod == 0):
+		m.setRulesCursor(m.rules.cursor + 1)
+	case key.Code == tea.KeyPgUp:
+		m.setRulesCursor(m.rules.cursor - m.ruleRows())
+	case key.Code == tea.KeyPgDown:
+		m.setRulesCursor(m.rules.cursor + m.ruleRows())
+	case key.Code == tea.KeyHome || (key.Text == "g" && key.Mod == 0):
+		m.setRulesCursor(0)
+	case key.Code == tea.KeyEnd || key.Text == "G":
+		m.setRulesCursor(len(m.rules.list) - 1)
+	case key.Text == "/":
+		m.rules.filter.active = true
+	case key.Text == "r" && key.Mod == 0:
+		return m, fetchRulesCmd(m.Backend)
+
+	case key.Code == tea.KeyEnter:
+		// Jump to the rule's target group on the proxies page
+		if m.rules.cursor < len(m.rules.list) {
+			return m, m.focusGroup(m.rules.list[m.rules.cursor].Proxy)
+		}
+	}
+	return m, nil
+}
+
+func (m Model) viewRules() string {
+	count := fmt.Sprintf("%d rules", len(m.rules.list))
+	if m.rules.filter.value != "" {
+		count = fmt.Sprintf("%d/%d rules", len(m.rules.list), len(m.rules.all))
+	}
+	s := selectedGroupStyle.Render(" "+count+" ") + "\n"
+	if m.rules.filterShown() {
+		s += m.fit(m.rules.filter.view("/")) + "\n"
+	}
+
+	switch {
+	case m.rules.err != nil:
+		return s + fixedIndicatorStyle.Render("  "+m.rules.err.Error()) + "\n"
+	case !m.rules.loaded:
+		return s + helpStyle.Render("  Loading rules...") + "\n"
+	case len(m.rules.list) == 0 && len(m.rules.all) > 0:
+		return s + helpStyle.Render("  No rules match the filter") + "\n"
+	case len(m.rules.list) == 0:
+		return s + helpStyle.Render("  No rules") + "\n"
+	}
+
+	end := m.rules.offset + m.ruleRows()
+	if end > len(m.rules.list) {
+		end = len(m.rules.list)
+	}
+	for i := m.rules.offset; i < end; i++ {
+		marker := "   "
+		if i == m.rules.cursor {
+			marker = cursorStyle.Render(">  ")
+		}
+		s += m.fit(marker+ruleLine(m.rules.list[i])) + "\n"
+	}
+	return s
+}
+
+func ruleLine(r clash.Rule) string {
+	if r.Extra != nil && r.Extra.Disabled {
+		return ruleDisabledStyle.Render(fmt.Sprintf("%-14s %s → %s", r.Type, r.Payload, r.Proxy)) + " " + helpStyle.Render("(disabled)")
+	}
+	line := ruleTypeStyle.Render(fmt.Sprintf("%-14s", r.Type)) + " "
+	if r.Payload != "" {
+		line += r.Payload + " "
+	}
+	line += separatorStyle.Render("→") + " " + ruleTargetStyle.Render(r.Proxy)
+
+	var extra []string
+	if r.Type == "RuleSet" && r.Size > 0 {
+		extra = append(extra, fmt.Sprintf("%d rules", r.Size))
+	}
+	if r.Extra != nil && r.Extra.HitCount > 0 {
+		extra = append(extra, fmt.Sprintf("%d hits", r.Extra.HitCount))
+	}
+	if len(extra) > 0 {
+		line += " " + helpStyle.Render("("+strings.Join(extra, ", ")+")")
+	}
+	return line
+}
@@ -0,0 +1,114 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/wallacegibbon/proxy-controller-tui/internal/clash"
+)
+
+const (
+	trafficHistoryLen = 16              // samples kept for the sparklines
+	streamRetryDelay  = 5 * time.Second // before reopening a failed stream
+)
+
+var (
+	trafficUpStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+	trafficDownStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("86"))
+)
+
+// trafficState follows the /traffic stream.
+type trafficState struct {
+	stream      *clash.Stream[clash.Traffic]
+	history     []clash.Traffic // oldest first, the last one is current
+	unsupported bool
+}
+
+type trafficOpenedMsg struct {
+	stream *clash.Stream[clash.Traffic]
+	err    error
+}
+
+type trafficMsg struct {
+	sample clash.Traffic
+	err    error
+}
+
+type trafficRetryMsg struct{}
+
+func openTrafficCmd(backend clash.Backend) tea.Cmd {
+	return func() tea.Msg {
+		stream, err := backend.StreamTrafficContext(context.Background())
+		return trafficOpenedMsg{stream: stream, err: err}
+	}
+}
+
+// nextTrafficCmd waits for one sample; Update asks for the next one, so the
+// stream becomes a steady flow of trafficMsg.
+func nextTrafficCmd(stream *clash.Stream[clash.Traffic]) tea.Cmd {
+	return func() tea.Msg {
+		sample, err := stream.Next()
+		return trafficMsg{sample: sample, err: err}
+	}
+}
+
+func trafficRetryCmd() tea.Cmd {
+	return tea.Tick(streamRetryDelay, func(time.Time) tea.Msg {
+		return trafficRetryMsg{}
+	})
+}
+
+func (m Model) handleTrafficOpened(msg trafficOpenedMsg) (Model, tea.Cmd) {
+	if msg.err != nil {
+		if clash.StatusCode(msg.err) == http.StatusNotFound {
+			m.traffic.unsupported = true
+			return m, nil
+		}
+		// Reconnecting is left to the proxies loop, just try again later
+		return m, trafficRetryCmd()
+	}
+	m.traffic.stream = msg.stream
+	return m, nextTrafficCmd(msg.stream)
+}
+
+func (m Model) handleTraffic(msg trafficMsg) (Model, tea.Cmd) {
+	if msg.err != nil {
+		m.traffic.stream.Close()
+		m.traffic.stream = nil
+		if errors.Is(msg.err, context.Canceled) {
+			return m, nil
+		}
+		return m, trafficRetryCmd()
+	}
+
+	first := len(m.traffic.history) == 0
+	m.traffic.history = append(m.traffic.history, msg.sample)
+	if n := len(m.traffic.history); n > trafficHistoryLen {
+		m.traffic.history = append([]clash.Traffic(nil), m.traffic.history[n-trafficHistoryLen:]...)
+	}
+	if first {
+		// The status line appears with the first sample
+		m.relayout()
+	}
+	return m, nextTrafficCmd(m.traffic.stream)
+}
+
+// trafficLine shows the current rates with their recent history, or
+// nothing before the first sample.
+func (m Model) trafficLine() string {
+	if len(m.traffic.history) == 0 {
+		return ""
+	}
+	up := make([]int64, len(m.traffic.history))
+	down := make([]int64, len(m.traffic.history))
+	for i, t := range m.traffic.history {
+		up[i], down[i] = t.Up, t.Down
+	}
+	cur := m.traffic.history[len(m.traffic.history)-1]
+	return trafficUpStyle.Render("↑ "+formatBytes(cur.Up)+"/s "+sparkline(up)) + "  " +
+		trafficDownStyle.Render("↓ "+formatBytes(cur.Down)+"/s "+sparkline(down))
+}
@@ -1,6 +1,11 @@
 package tui
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
 	tea "charm.land/bubbletea/v2"
 	"github.com/wallacegibbon/proxy-controller-tui/internal/clash"
 )
@@ -8,22 +13,174 @@ import (
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case errMsg:
-		m.Loading = false
-		m.Err = msg
+		// Cancelled requests were already handled by Esc or superseded
+		if errors.Is(msg, context.Canceled) {
+			return m, nil
+		}
+		return m.handleError(msg)
+
+	case reconnectMsg:
+		return m.handleReconnect(msg)
+
+	case versionMsg:
+		m.Version = msg.version
 		return m, nil
 
+	case configMsg:
+		return m.handleConfig(msg)
+
+	case modeChangedMsg:
+		return m.handleModeChanged(msg)
+
+	case reloadMsg:
+		m.Loading = true
+		return m, LoadProxiesCmd(m.beginRequest(), m.Backend)
+
 	case resetFixedMsg:
-		m.Loading = false
-		// Reload proxies after reset attempt
-		return m, LoadProxiesCmd(m.Client)
+		if msg.err != nil {
+			if errors.Is(msg.err, context.Canceled) {
+				return m, nil
+			}
+			return m.handleError(msg.err)
+		}
+		m.Loading = true
+		return m, LoadProxiesCmd(m.beginRequest(), m.Backend)
+
+	case selectProxyMsg:
+		if msg.err != nil {
+			if errors.Is(msg.err, context.Canceled) {
+				return m, nil
+			}
+			return m.handleError(msg.err)
+		}
+		reload := loadProxiesWithDelayCmd(m.beginRequest(), m.Backend)
+		if m.Options.CloseConnectionsOnSwitch {
+			// Long-lived connections would otherwise stay on the old proxy
+			return m, tea.Batch(reload, closeGroupConnectionsCmd(m.Backend, msg.groupName))
+		}
+		return m, reload
+
+	case groupDelayMsg:
+		// A cancelled test may answer after the next one started
+		if msg.seq != m.delaySeq || m.testingGroup == "" {
+			return m, nil
+		}
+		m.delayCancel()
+		m.delayCancel = nil
+		m.testingGroup = ""
+		if msg.err != nil {
+			if errors.Is(msg.err, context.Canceled) {
+				return m, nil
+			}
+			// Mihomo answers 504 when no member responds; the list stays usable
+			return m, m.setNotice(fmt.Sprintf("Delay test of %s failed: %v", msg.groupName, msg.err), true)
+		}
+		if m.Delays == nil {
+			m.Delays = make(map[string]int)
+		}
+		// Members missing from the result failed the test
+		if proxy, ok := m.Proxies[msg.groupName]; ok {
+			for _, p := range proxy.All {
+				m.Delays[p] = msg.delays[p]
+			}
+		}
+		return m, nil
+
+	case spinnerTickMsg:
+		if m.testingGroup == "" {
+			return m, nil
+		}
+		m.spinnerFrame = (m.spinnerFrame + 1) % len(spinnerFrames)
+		return m, spinnerTickCmd()
+
+	case connectionsMsg:
+		return m.handleConnectionsMsg(msg)
+
+	case connectionsTickMsg:
+		return m.handleConnectionsTick(msg)
+
+	case connectionsClosedMsg:
+		return m.handleConnectionsClosed(msg)
+
+	case trafficOpenedMsg:
+		return m.handleTrafficOpened(msg)
+
+	case trafficMsg:
+		return m.handleTraffic(msg)
+
+	case trafficRetryMsg:
+		return m, openTrafficCmd(m.Backend)
+
+	case memoryOpenedMsg:
+		return m.handleMemoryOpened(msg)
+
+	case memoryMsg:
+		return m.handleMemory(msg)
+
+	case memoryRetryMsg:
+		return m, openMemoryCmd(m.Backend)
+
+	case rulesMsg:
+		return m.handleRulesMsg(msg)
+
+	case providersMsg:
+		return m.handleProvidersMsg(msg)
+
+	case providerActionMsg:
+		return m.handleProviderAction(msg)
+
+	case ruleProvidersMsg:
+		return m.handleRuleProvidersMsg(msg)
+
+	case ruleProviderUpdatedMsg:
+		return m.handleRuleProviderUpdated(msg)
+
+	case configLoadedMsg:
+		return m.handleConfigLoaded(msg)
+
+	case configAppliedMsg:
+		return m.handleConfigApplied(msg)
+
+	case coreActionMsg:
+		return m.handleCoreAction(msg)
+
+	case coreBackMsg:
+		return m.handleCoreBack(msg)
+
+	case cacheFlushedMsg:
+		return m.handleCacheFlushed(msg)
+
+	case logsOpenedMsg:
+		return m.handleLogsOpened(msg)
+
+	case logMsg:
+		return m.handleLog(msg)
+
+	case logsRetryMsg:
+		return m.handleLogsRetry(msg)
+
+	case logsSavedMsg:
+		return m.handleLogsSaved(msg)
+
+	case noticeExpiredMsg:
+		return m.handleNoticeExpired(msg)
 
 	case tea.WindowSizeMsg:
 		m.Height = msg.Height
-		m.adjustViewport()
+		m.Width = msg.Width
+		m.relayout()
 		return m, nil
 
 	case proxiesLoadedMsg:
+		var cmd tea.Cmd
+		if m.Conn != Connected {
+			// The core may have been restarted or replaced
+			cmd = tea.Batch(versionCmd(m.Backend), configCmd(m.Backend))
+		}
+		m.cancelRequest()
+		m.markConnected()
 		m.Loading = false
+		m.Err = nil
 		m.Proxies = msg.proxies
 		m.Groups = msg.groups
 		if m.CurrentIdx >= len(m.Groups) {
@@ -57,14 +214,64 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.updateLastCursorProxy()
 			}
 		}
+		m.applyGlobalFocus()
 		m.adjustViewport()
-		return m, nil
+		return m, cmd
 
 	case tea.KeyPressMsg:
 		if m.Loading {
+			switch key := msg.Key(); {
+			case key.Code == tea.KeyEscape:
+				// Abandon the in-flight request; keep showing old data if any
+				m.cancelRequest()
+				m.Loading = false
+				if len(m.Groups) == 0 {
+					m.Err = errRequestCanceled
+				}
+			case key.Text == "q" && key.Mod == 0:
+				m.cancelRequest()
+				return m, tea.Quit
+			}
 			return m, nil
 		}
 
+		if m.confirm != nil {
+			return m.handleConfirmKey(msg)
+		}
+		if in := m.focusedInput(); in != nil {
+			in.update(msg.Key())
+			m.inputChanged()
+			return m, nil
+		}
+		if m.Err != nil {
+			// The error screen only offers retry and quit
+			switch key := msg.Key(); {
+			case key.Text == "r" && key.Mod == 0:
+				m.Loading = true
+				return m, LoadProxiesCmd(m.beginRequest(), m.Backend)
+			case key.Text == "q" && key.Mod == 0:
+				return m, tea.Quit
+			}
+			return m, nil
+		}
+		if next, cmd, ok := m.handleGlobalKey(msg); ok {
+			return next, cmd
+		}
+		switch m.page {
+		case pageConnections:
+			return m.updateConnections(msg)
+		case pageLogs:
+			return m.updateLogs(msg)
+		case pageRules:
+			return m.updateRules(msg)
+		case pageProviders:
+			return m.updateProviders(msg)
+		case pageRuleProviders:
+			return m.updateRuleProviders(msg)
+		case pageConfig:
+			return m.updateConfig(msg)
+		}
+
 		switch key := msg.Key(); {
 		case key.Code == tea.KeyUp || (key.Text == "k" && key.Mod == 0):
 			if m.CurrentIdx < len(m.Groups) {
@@ -103,30 +310,51 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				group := m.Groups[m.CurrentIdx]
 				if proxy, ok := m.Proxies[group]; ok && m.Cursor < len(proxy.All) {
 					selectedProxy := proxy.All[m.Cursor]
-					if err := m.Client.SelectProxy(group, selectedProxy); err != nil {
-						m.Err = err
-						return m, nil
-					}
-					return m, loadProxiesWithDelayCmd(m.Client)
+					return m, selectProxyCmd(m.beginRequest(), m.Backend, group, selectedProxy)
 				}
 			}
 			return m, nil
 
-		case key.Text == "q" && key.Mod == 0:
-			return m, tea.Quit
+		case key.Code == tea.KeyEscape:
+			// Abandon a proxy switch still in flight; the list stays as is
+			m.cancelRequest()
+			if m.delayCancel != nil {
+				m.delayCancel()
+				m.delayCancel = nil
+				m.testingGroup = ""
+			}
+			return m, nil
+
+		case key.Text == "t" && key.Mod == 0:
+			// Test the delay of every proxy in the current group
+			if !m.caps().GroupDelay {
+				return m, m.unsupported("Group delay testing")
+			}
+			if m.testingGroup == "" && m.CurrentIdx < len(m.Groups) {
+				group := m.Groups[m.CurrentIdx]
+				ctx, cancel := context.WithCancel(context.Background())
+				m.delayCancel = cancel
+				m.testingGroup = group
+				m.delaySeq++
+				return m, tea.Batch(groupDelayCmd(ctx, m.Backend, group, m.delaySeq), spinnerTickCmd())
+			}
+			return m, nil
 
 		case key.Text == "r" && key.Mod == 0:
 			m.Loading = true
-			return m, LoadProxiesCmd(m.Client)
+			return m, LoadProxiesCmd(m.beginRequest(), m.Backend)
 
 		case key.Text == "a" && key.Mod == 0:
 			// Reset fixed proxy for URLTest groups (restore auto-selection)
+			if !m.caps().UnfixProxy {
+				return m, m.unsupported("Unfixing a URLTest group")
+			}
 			if m.CurrentIdx < len(m.Groups) {
 				group := m.Groups[m.CurrentIdx]
 				if proxy, ok := m.Proxies[group]; ok && proxy.Type == "URLTest" {
 					if proxy.Fixed != "" {
 						m.Loading = true
-						return m, resetFixedCmd(m.Client, group)
+						return m, resetFixedCmd(m.beginRequest(), m.Backend, group)
 					}
 				}
 			}
@@ -136,9 +364,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
-func resetFixedCmd(client *clash.Client, groupName string) tea.Cmd {
+func resetFixedCmd(ctx context.Context, backend clash.Backend, groupName string) tea.Cmd {
 	return func() tea.Msg {
-		err := client.ResetFixedProxy(groupName)
+		err := backend.ResetFixedProxyContext(ctx, groupName)
 		return resetFixedMsg{
 			groupName: groupName,
 			err:       err,
@@ -146,27 +374,59 @@ func resetFixedCmd(client *clash.Client, groupName string) tea.Cmd {
 	}
 }
 
+func selectProxyCmd(ctx context.Context, backend clash.Backend, groupName, proxyName string) tea.Cmd {
+	return func() tea.Msg {
+		err := backend.SelectProxyContext(ctx, groupName, proxyName)
+		return selectProxyMsg{
+			groupName: groupName,
+			proxyName: proxyName,
+			err:       err,
+		}
+	}
+}
+
 func (m *Model) navigateGroup(direction int) (tea.Model, tea.Cmd) {
 	newIdx := m.CurrentIdx + direction
 	if newIdx >= 0 && newIdx < len(m.Groups) {
-		m.CurrentIdx = newIdx
-		group := m.Groups[m.CurrentIdx]
-		if proxy, ok := m.Proxies[group]; ok {
-			for i, p := range proxy.All {
-				if p == proxy.Now {
-					m.Cursor = i
-					m.updateLastCursorProxy()
-					break
-				}
+		m.selectGroup(newIdx)
+	}
+	return *m, nil
+}
+
+// selectGroup makes m.Groups[idx] the current group, with the cursor on its
+// active proxy.
+func (m *Model) selectGroup(idx int) {
+	m.CurrentIdx = idx
+	group := m.Groups[m.CurrentIdx]
+	if proxy, ok := m.Proxies[group]; ok {
+		for i, p := range proxy.All {
+			if p == proxy.Now {
+				m.Cursor = i
+				m.updateLastCursorProxy()
+				break
 			}
-		} else {
-			m.Cursor = 0
-			m.lastCursorProxy = ""
 		}
-		m.ViewportOffset = 0
-		m.adjustViewport()
+	} else {
+		m.Cursor = 0
+		m.lastCursorProxy = ""
 	}
-	return *m, nil
+	m.ViewportOffset = 0
+	m.adjustViewport()
+}
+
+// focusGroup shows the named group on the proxies page. When it cannot, the
+// returned notice says why: the name is not a group, e.g. DIRECT or a single
+// proxy, or it is a group the page does not list, like Fallback.
+func (m *Model) focusGroup(name string) tea.Cmd {
+	if i := slices.Index(m.Groups, name); i >= 0 {
+		m.page = pageProxies
+		m.selectGroup(i)
+		return nil
+	}
+	if proxy, ok := m.Proxies[name]; ok && len(proxy.All) > 0 {
+		return m.setNotice(fmt.Sprintf("%s is a %s group; only Selector and URLTest groups are listed", name, proxy.Type), true)
+	}
+	return m.setNotice(name+" is not a proxy group", true)
 }
 
 func (m *Model) updateLastCursorProxy() {
@@ -188,10 +448,7 @@ func (m *Model) adjustViewport() {
 		return
 	}
 
-	maxProxyLines := m.Height - 1
-	if maxProxyLines < 1 {
-		maxProxyLines = 1
-	}
+	maxProxyLines := m.maxProxyLines()
 
 	visibleCount := maxProxyLines
 	if visibleCount > len(proxy.All) {
@@ -216,3 +473,24 @@ func (m *Model) adjustViewport() {
 		m.ViewportOffset = maxOffset
 	}
 }
+
+// relayout keeps the cursors visible after the rows available to the lists
+// changed, e.g. when a header line appeared.
+func (m *Model) relayout() {
+	m.adjustViewport()
+	m.setConnectionsCursor(m.conns.cursor)
+	m.setLogsCursor(m.logs.cursor)
+	m.setRulesCursor(m.rules.cursor)
+	m.setProvidersCursor(m.providers.cursor)
+	m.setRuleProvidersCursor(m.ruleProviders.cursor)
+}
+
+// maxProxyLines is the number of rows left for proxies below the group bar
+// and any banner lines.
+func (m Model) maxProxyLines() int {
+	n := m.Height - 1 - len(m.headerLines())
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
@@ -1,7 +1,12 @@
 package tui
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"os"
+	"syscall"
 
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
@@ -11,13 +16,15 @@ import (
 var (
 	fixedIndicatorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
 	helpStyle           = lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
+	hintStyle           = lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
 )
 
 func (m Model) View() tea.View {
 	if m.Loading {
 		v := tea.NewView(
 			separatorStyle.Render("═══════════════════════════════════════") + "\n" +
-				headerStyle.Render("  Loading proxies..."),
+				headerStyle.Render("  Loading proxies...") + "\n" +
+				helpStyle.Render("  Press [esc] cancel"),
 		)
 		v.AltScreen = true
 		return v
@@ -28,12 +35,38 @@ func (m Model) View() tea.View {
 			separatorStyle.Render("═══════════════════════════════════════") + "\n" +
 				headerStyle.Render("  Error") + "\n" +
 				fmt.Sprintf("  %v\n", m.Err) +
+				hintStyle.Render("  "+m.errorHint()) + "\n" +
+				m.retryLine() +
 				helpStyle.Render("  Press [r] retry, [q] quit"),
 		)
 		v.AltScreen = true
 		return v
 	}
 
+	if m.page != pageProxies {
+		var s string
+		for _, line := range m.headerLines() {
+			s += line + "\n"
+		}
+		switch m.page {
+		case pageConnections:
+			s += m.viewConnections()
+		case pageLogs:
+			s += m.viewLogs()
+		case pageRules:
+			s += m.viewRules()
+		case pageProviders:
+			s += m.viewProviders()
+		case pageRuleProviders:
+			s += m.viewRuleProviders()
+		case pageConfig:
+			s += m.viewConfig()
+		}
+		v := tea.NewView(s)
+		v.AltScreen = true
+		return v
+	}
+
 	if len(m.Groups) == 0 {
 		v := tea.NewView(
 			separatorStyle.Render("═══════════════════════════════════════") + "\n" +
@@ -52,6 +85,9 @@ func (m Model) View() tea.View {
 	}
 
 	var s string
+	for _, line := range m.headerLines() {
+		s += line + "\n"
+	}
 
 	// Show only the selected group with navigation indicators
 	if selectedOk {
@@ -76,14 +112,15 @@ func (m Model) View() tea.View {
 			suffix = " >>"
 		}
 
+		if m.testingGroup == group {
+			groupWithType += " " + spinnerFrames[m.spinnerFrame] + " testing"
+		}
+
 		s += selectedGroupStyle.Render(prefix+groupWithType+suffix) + "\n"
 
 		// Render proxies
 		if len(selectedProxy.All) > 0 {
-			maxProxyLines := m.Height - 1
-			if maxProxyLines < 1 {
-				maxProxyLines = 1
-			}
+			maxProxyLines := m.maxProxyLines()
 
 			totalProxies := len(selectedProxy.All)
 			visibleCount := maxProxyLines
@@ -115,6 +152,9 @@ func (m Model) View() tea.View {
 				} else {
 					line = "   " + normalStyle.Render(p)
 				}
+				if d, ok := m.Delays[p]; ok {
+					line += " " + renderDelay(d)
+				}
 				if actualIdx == m.Cursor && totalProxies > visibleCount {
 					line += helpStyle.Render(fmt.Sprintf(" (%d/%d)", m.Cursor+1, totalProxies))
 				}
@@ -128,3 +168,91 @@ func (m Model) View() tea.View {
 	v.MouseMode = tea.MouseModeCellMotion
 	return v
 }
+
+// renderDelay formats a delay test result; 0 means the test failed.
+func renderDelay(delay int) string {
+	switch {
+	case delay <= 0:
+		return slowDelayStyle.Render("timeout")
+	case delay < 200:
+		return fastDelayStyle.Render(fmt.Sprintf("%dms", delay))
+	case delay < 500:
+		return mediumDelayStyle.Render(fmt.Sprintf("%dms", delay))
+	default:
+		return slowDelayStyle.Render(fmt.Sprintf("%dms", delay))
+	}
+}
+
+// errorHint suggests what to do about the error on screen.
+func (m Model) errorHint() string {
+	err := m.Err
+	var (
+		unauthorized *clash.UnauthorizedError
+		unreachable  *clash.UnreachableError
+		noGroup      *clash.GroupNotFoundError
+		notInGroup   *clash.ProxyNotInGroupError
+		decode       *clash.DecodeError
+		status       *clash.StatusError
+		certInvalid  *tls.CertificateVerificationError
+	)
+	switch {
+	case errors.Is(err, errRequestCanceled):
+		return "The request was cancelled."
+	case errors.As(err, &unauthorized):
+		return "Set MIHOMO_SECRET (or --secret) to the secret in the core's config."
+	case errors.As(err, &unreachable):
+		switch {
+		case errors.Is(err, syscall.ECONNREFUSED):
+			return "Connection refused: is external-controller enabled and the address right?"
+		case errors.Is(err, os.ErrNotExist) && !m.caps().UnixSocket:
+			// Known from before the connection was lost, e.g. a restart
+			return "Socket not found: " + m.Version.String() + " has no external-controller-unix, use its TCP address."
+		case errors.Is(err, os.ErrNotExist):
+			return "Socket not found: is external-controller-unix set to this path?"
+		case errors.Is(err, context.DeadlineExceeded):
+			return "No answer in time: check the address and firewall, or raise --timeout."
+		case errors.As(err, &certInvalid):
+			return "TLS verification failed: use --ca-file or --fingerprint for self-signed certificates."
+		}
+		return "Check the controller address (--controller or CLASH_CONTROLLER)."
+	case errors.As(err, &noGroup), errors.As(err, &notInGroup):
+		return "The proxy groups changed since they were loaded, reload to refresh."
+	case errors.As(err, &decode):
+		return "Unexpected response: does the address point to a Clash/Mihomo controller?"
+	case errors.As(err, &status) && status.StatusCode == 404:
+		return "Endpoint not found: the core may not support this feature."
+	}
+	return "Check the core's logs for details."
+}
+
+// headerLines are rendered above the group bar.
+func (m Model) headerLines() []string {
+	// Cut rather than wrap, the lists count on one row per header line
+	lines := []string{m.fit(m.tabsLine())}
+	if traffic := m.trafficLine(); traffic != "" {
+		lines = append(lines, traffic)
+	}
+	if banner := m.connBanner(); banner != "" {
+		lines = append(lines, banner)
+	}
+	if waiting := m.waitingLine(); waiting != "" {
+		lines = append(lines, waiting)
+	}
+	return append(lines, m.promptLines()...)
+}
+
+// retryLine tells how the error screen will recover on its own, if it will.
+func (m Model) retryLine() string {
+	if m.Conn == Connected {
+		return ""
+	}
+	return "  " + m.connBanner() + "\n"
+}
+
+// fit cuts a line to the terminal width.
+func (m Model) fit(line string) string {
+	if m.Width <= 0 {
+		return line
+	}
+	return lipgloss.NewStyle().MaxWidth(m.Width).Render(line)
+}
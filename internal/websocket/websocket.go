@@ -0,0 +1,252 @@
+// Package websocket implements the part of RFC 6455 needed by the
+// controller's streaming endpoints: handshake keys, framing, and a Conn that
+// reads whole messages and answers pings.
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Frame opcodes.
+const (
+	OpContinuation byte = 0x0
+	OpText         byte = 0x1
+	OpBinary       byte = 0x2
+	OpClose        byte = 0x8
+	OpPing         byte = 0x9
+	OpPong         byte = 0xA
+)
+
+const (
+	acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	// MaxMessageSize bounds a single message, frames above it are refused.
+	MaxMessageSize = 16 << 20
+)
+
+// ErrMessageTooLarge is returned for messages above MaxMessageSize.
+var ErrMessageTooLarge = errors.New("websocket: message too large")
+
+// writeTimeout bounds each write on connections that support deadlines, so
+// a stalled peer cannot hold the write lock forever. A variable for tests.
+var writeTimeout = 5 * time.Second
+
+// NewKey returns a random Sec-WebSocket-Key.
+func NewKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// AcceptKey returns the Sec-WebSocket-Accept value for a client key.
+func AcceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// Frame is a single WebSocket frame with its payload unmasked.
+type Frame struct {
+	Fin     bool
+	Op      byte
+	Payload []byte
+}
+
+// ReadFrame reads one frame, unmasking the payload if needed.
+func ReadFrame(r io.Reader) (Frame, error) {
+	var hdr [2]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return Frame{}, err
+	}
+	f := Frame{Fin: hdr[0]&0x80 != 0, Op: hdr[0] & 0x0F}
+	masked := hdr[1]&0x80 != 0
+	if hdr[0]&0x70 != 0 {
+		// No extension is negotiated, so RSV1-3 must be clear
+		return Frame{}, errors.New("websocket: reserved bits set")
+	}
+
+	n := uint64(hdr[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return Frame{}, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return Frame{}, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > MaxMessageSize {
+		return Frame{}, ErrMessageTooLarge
+	}
+	if f.Op&0x8 != 0 && (!f.Fin || n > 125) {
+		// Control frames may appear between fragments but are never
+		// fragmented themselves
+		return Frame{}, fmt.Errorf("websocket: invalid control frame %#x", f.Op)
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return Frame{}, err
+		}
+	}
+	f.Payload = make([]byte, n)
+	if _, err := io.ReadFull(r, f.Payload); err != nil {
+		return Frame{}, err
+	}
+	if masked {
+		for i := range f.Payload {
+			f.Payload[i] ^= mask[i%4]
+		}
+	}
+	return f, nil
+}
+
+// WriteFrame writes one final frame. Clients must mask, servers must not.
+func WriteFrame(w io.Writer, op byte, payload []byte, masked bool) error {
+	hdr := []byte{0x80 | op, 0}
+	n := len(payload)
+	switch {
+	case n < 126:
+		hdr[1] = byte(n)
+	case n <= 0xFFFF:
+		hdr[1] = 126
+		hdr = binary.BigEndian.AppendUint16(hdr, uint16(n))
+	default:
+		hdr[1] = 127
+		hdr = binary.BigEndian.AppendUint64(hdr, uint64(n))
+	}
+
+	body := payload
+	if masked {
+		hdr[1] |= 0x80
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+		hdr = append(hdr, mask[:]...)
+		body = make([]byte, n)
+		for i := range payload {
+			body[i] = payload[i] ^ mask[i%4]
+		}
+	}
+
+	if _, err := w.Write(append(hdr, body...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Conn is an established WebSocket connection.
+type Conn struct {
+	r      *bufio.Reader
+	w      io.WriteCloser
+	client bool
+
+	mu     sync.Mutex // serialises writes
+	closed bool
+}
+
+// NewConn wraps an upgraded connection. r and w are usually the same
+// connection; r may be a buffered reader that already holds data. client
+// selects the masking side.
+func NewConn(r io.Reader, w io.WriteCloser, client bool) *Conn {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &Conn{r: br, w: w, client: client}
+}
+
+// ReadMessage returns the next text or binary message. Pings are answered
+// on the way; a close frame from the peer ends the stream with io.EOF.
+func (c *Conn) ReadMessage() ([]byte, error) {
+	var msg []byte
+	started := false
+	for {
+		f, err := ReadFrame(c.r)
+		if err != nil {
+			return nil, err
+		}
+		switch f.Op {
+		case OpPing:
+			if err := c.write(OpPong, f.Payload); err != nil {
+				return nil, err
+			}
+			continue
+		case OpPong:
+			continue
+		case OpClose:
+			// Echo the close and release the connection
+			c.Close()
+			return nil, io.EOF
+		case OpText, OpBinary:
+			if started {
+				return nil, fmt.Errorf("websocket: new message inside a fragmented one")
+			}
+			started = true
+		case OpContinuation:
+			if !started {
+				return nil, fmt.Errorf("websocket: continuation without a message")
+			}
+		default:
+			return nil, fmt.Errorf("websocket: unknown opcode %#x", f.Op)
+		}
+		if len(msg)+len(f.Payload) > MaxMessageSize {
+			return nil, ErrMessageTooLarge
+		}
+		msg = append(msg, f.Payload...)
+		if f.Fin {
+			return msg, nil
+		}
+	}
+}
+
+// WriteMessage sends a message in a single frame.
+func (c *Conn) WriteMessage(op byte, payload []byte) error {
+	return c.write(op, payload)
+}
+
+func (c *Conn) write(op byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return io.ErrClosedPipe
+	}
+	return c.writeFrame(op, payload)
+}
+
+// writeFrame writes under c.mu, with a deadline when the connection has one.
+func (c *Conn) writeFrame(op byte, payload []byte) error {
+	if d, ok := c.w.(interface{ SetWriteDeadline(time.Time) error }); ok {
+		d.SetWriteDeadline(time.Now().Add(writeTimeout))
+	}
+	return WriteFrame(c.w, op, payload, c.client)
+}
+
+// Close sends a close frame, best effort, and closes the connection.
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	c.writeFrame(OpClose, nil)
+	return c.w.Close()
+}
@@ -0,0 +1,328 @@
+package websocket
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcceptKey(t *testing.T) {
+	// Example from RFC 6455 section 1.3
+	if got := AcceptKey("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Unexpected accept key %q", got)
+	}
+}
+
+func TestFrameRoundTrip(t *testing.T) {
+	for _, size := range []int{0, 5, 125, 126, 1000, 70000} {
+		payload := bytes.Repeat([]byte("x"), size)
+		for _, masked := range []bool{false, true} {
+			var buf bytes.Buffer
+			if err := WriteFrame(&buf, OpText, payload, masked); err != nil {
+				t.Fatal(err)
+			}
+			f, err := ReadFrame(&buf)
+			if err != nil {
+				t.Fatalf("size %d masked %v: %v", size, masked, err)
+			}
+			if !f.Fin || f.Op != OpText || !bytes.Equal(f.Payload, payload) {
+				t.Errorf("size %d masked %v: frame did not round-trip", size, masked)
+			}
+		}
+	}
+}
+
+func TestConnFragmentsAndPings(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		// A fragmented message with a ping in between, then a close
+		server.Write([]byte{0x01, 3, 'a', 'b', 'c'})
+		server.Write([]byte{0x89, 0})
+		if f, _ := ReadFrame(server); f.Op != OpPong {
+			return
+		}
+		server.Write([]byte{0x80, 3, 'd', 'e', 'f'})
+		server.Write([]byte{0x88, 0})
+		io.Copy(io.Discard, server)
+	}()
+
+	c := NewConn(client, client, true)
+	msg, err := c.ReadMessage()
+	if err != nil || string(msg) != "abcdef" {
+		t.Fatalf("Expected reassembled message, got %q, %v", msg, err)
+	}
+	if _, err := c.ReadMessage(); err != io.EOF {
+		t.Errorf("Expected io.EOF after close, got %v", err)
+	}
+}
+
+func TestMessageTooLarge(t *testing.T) {
+	hdr := []byte{0x81, 127, 0, 0, 0, 0, 0x10, 0, 0, 1}
+	if _, err := ReadFrame(strings.NewReader(string(hdr))); err != ErrMessageTooLarge {
+		t.Errorf("Expected ErrMessageTooLarge, got %v", err)
+	}
+}
+
+// The examples of RFC 6455 section 5.7.
+func TestRFCExamples(t *testing.T) {
+	tests := []struct {
+		name    string
+		wire    []byte
+		op      byte
+		fin     bool
+		payload string
+	}{
+		{"unmasked text", []byte{0x81, 0x05, 0x48, 0x65, 0x6c, 0x6c, 0x6f}, OpText, true, "Hello"},
+		{"masked text", []byte{0x81, 0x85, 0x37, 0xfa, 0x21, 0x3d, 0x7f, 0x9f, 0x4d, 0x51, 0x58}, OpText, true, "Hello"},
+		{"first fragment", []byte{0x01, 0x03, 0x48, 0x65, 0x6c}, OpText, false, "Hel"},
+		{"last fragment", []byte{0x80, 0x02, 0x6c, 0x6f}, OpContinuation, true, "lo"},
+		{"unmasked ping", []byte{0x89, 0x05, 0x48, 0x65, 0x6c, 0x6c, 0x6f}, OpPing, true, "Hello"},
+		{"masked pong", []byte{0x8a, 0x85, 0x37, 0xfa, 0x21, 0x3d, 0x7f, 0x9f, 0x4d, 0x51, 0x58}, OpPong, true, "Hello"},
+	}
+	for _, tt := range tests {
+		f, err := ReadFrame(bytes.NewReader(tt.wire))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if f.Op != tt.op || f.Fin != tt.fin || string(f.Payload) != tt.payload {
+			t.Errorf("%s: got %+v", tt.name, f)
+		}
+	}
+}
+
+func TestExtendedLengths(t *testing.T) {
+	tests := []struct {
+		size int
+		hdr  []byte // header of an unmasked binary frame
+	}{
+		{125, []byte{0x82, 125}},
+		{126, []byte{0x82, 126, 0x00, 0x7e}},
+		{256, []byte{0x82, 126, 0x01, 0x00}},
+		{65535, []byte{0x82, 126, 0xff, 0xff}},
+		{65536, []byte{0x82, 127, 0, 0, 0, 0, 0, 0x01, 0x00, 0x00}},
+		{70000, []byte{0x82, 127, 0, 0, 0, 0, 0, 0x01, 0x11, 0x70}},
+	}
+	for _, tt := range tests {
+		payload := bytes.Repeat([]byte{0xA5}, tt.size)
+		var buf bytes.Buffer
+		if err := WriteFrame(&buf, OpBinary, payload, false); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.Bytes()[:len(tt.hdr)]; !bytes.Equal(got, tt.hdr) || buf.Len() != len(tt.hdr)+tt.size {
+			t.Errorf("size %d: got header % x", tt.size, got)
+		}
+
+		// A masked client frame has the mask bit and key after the length
+		buf.Reset()
+		if err := WriteFrame(&buf, OpBinary, payload, true); err != nil {
+			t.Fatal(err)
+		}
+		wire := buf.Bytes()
+		if wire[1]&0x80 == 0 || wire[1]&0x7F != tt.hdr[1] || len(wire) != len(tt.hdr)+4+tt.size {
+			t.Errorf("size %d: bad masked header % x", tt.size, wire[:len(tt.hdr)+4])
+		}
+		f, err := ReadFrame(&buf)
+		if err != nil || !bytes.Equal(f.Payload, payload) {
+			t.Errorf("size %d: masked frame did not round-trip: %v", tt.size, err)
+		}
+	}
+
+	// The most significant bit of a 64-bit length must be 0
+	hdr := []byte{0x82, 127, 0x80, 0, 0, 0, 0, 0, 0, 1}
+	if _, err := ReadFrame(bytes.NewReader(hdr)); err != ErrMessageTooLarge {
+		t.Errorf("Expected ErrMessageTooLarge, got %v", err)
+	}
+
+	// A truncated extended length is an error, not a short frame
+	if _, err := ReadFrame(bytes.NewReader([]byte{0x82, 127, 0, 0, 0})); err == nil {
+		t.Errorf("Expected an error for a truncated length")
+	}
+}
+
+func TestInvalidFrames(t *testing.T) {
+	tests := []struct {
+		name string
+		wire []byte
+	}{
+		{"reserved bit", []byte{0xC1, 0x01, 'a'}},
+		{"fragmented ping", []byte{0x09, 0x00}},
+		{"fragmented close", []byte{0x08, 0x00}},
+		{"long ping", append([]byte{0x89, 126, 0x00, 0x7e}, make([]byte, 126)...)},
+	}
+	for _, tt := range tests {
+		if _, err := ReadFrame(bytes.NewReader(tt.wire)); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+// readFrom feeds wire to a client Conn and returns what ReadMessage gives,
+// discarding anything the client writes back.
+func readFrom(wire ...[]byte) ([][]byte, error) {
+	var in bytes.Buffer
+	for _, w := range wire {
+		in.Write(w)
+	}
+	c := NewConn(&in, nopCloser{io.Discard}, true)
+	var msgs [][]byte
+	for {
+		msg, err := c.ReadMessage()
+		if err != nil {
+			return msgs, err
+		}
+		msgs = append(msgs, msg)
+	}
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+func TestConnFragmentation(t *testing.T) {
+	// Many fragments, an empty one, control frames in between and a
+	// second message after
+	msgs, err := readFrom(
+		[]byte{0x02, 2, 0x00, 0x01},
+		[]byte{0x8a, 0},
+		[]byte{0x00, 0},
+		[]byte{0x89, 2, 'h', 'i'},
+		[]byte{0x00, 1, 0x02},
+		[]byte{0x80, 1, 0x03},
+		[]byte{0x81, 2, 'o', 'k'},
+		[]byte{0x88, 0},
+	)
+	if err != io.EOF {
+		t.Fatalf("Expected io.EOF after close, got %v", err)
+	}
+	if len(msgs) != 2 || !bytes.Equal(msgs[0], []byte{0, 1, 2, 3}) || string(msgs[1]) != "ok" {
+		t.Errorf("Unexpected messages %q", msgs)
+	}
+
+	// A close frame in the middle of a message ends the stream
+	msgs, err = readFrom([]byte{0x01, 1, 'a'}, []byte{0x88, 0})
+	if err != io.EOF || len(msgs) != 0 {
+		t.Errorf("Expected io.EOF without a message, got %q, %v", msgs, err)
+	}
+
+	// A fragmented message may grow past the limit only frame by frame
+	half := MaxMessageSize/2 + 1
+	big := func(op byte, fin bool) []byte {
+		var buf bytes.Buffer
+		WriteFrame(&buf, op, make([]byte, half), false)
+		b := buf.Bytes()
+		if !fin {
+			b[0] &^= 0x80
+		}
+		return b
+	}
+	if _, err := readFrom(big(OpBinary, false), big(OpContinuation, true)); err != ErrMessageTooLarge {
+		t.Errorf("Expected ErrMessageTooLarge, got %v", err)
+	}
+
+	for name, wire := range map[string][][]byte{
+		"continuation first": {{0x80, 1, 'a'}},
+		"text inside text":   {{0x01, 1, 'a'}, {0x81, 1, 'b'}},
+		"unknown opcode":     {{0x83, 0}},
+	} {
+		if _, err := readFrom(wire...); err == nil || err == io.EOF {
+			t.Errorf("%s: expected a protocol error, got %v", name, err)
+		}
+	}
+}
+
+func TestConnMasking(t *testing.T) {
+	// Clients mask what they send, servers do not
+	for _, client := range []bool{true, false} {
+		var out bytes.Buffer
+		c := NewConn(strings.NewReader(""), nopCloser{&out}, client)
+		if err := c.WriteMessage(OpText, []byte("Hello")); err != nil {
+			t.Fatal(err)
+		}
+		wire := out.Bytes()
+		if masked := wire[1]&0x80 != 0; masked != client {
+			t.Errorf("client %v: mask bit %v", client, masked)
+		}
+		f, err := ReadFrame(&out)
+		if err != nil || string(f.Payload) != "Hello" {
+			t.Errorf("client %v: %q, %v", client, f.Payload, err)
+		}
+	}
+}
+
+func FuzzReadFrame(f *testing.F) {
+	f.Add([]byte{0x81, 0x05, 0x48, 0x65, 0x6c, 0x6c, 0x6f})
+	f.Add([]byte{0x82, 126, 0x00, 0x7e})
+	f.Add([]byte{0x82, 127, 0, 0, 0, 0, 0, 0x01, 0x00, 0x00})
+	f.Fuzz(func(t *testing.T, wire []byte) {
+		fr, err := ReadFrame(bytes.NewReader(wire))
+		if err != nil {
+			return
+		}
+		// Anything accepted is written back identically, unmasked
+		var buf bytes.Buffer
+		if err := WriteFrame(&buf, fr.Op, fr.Payload, false); err != nil {
+			t.Fatal(err)
+		}
+		again, err := ReadFrame(&buf)
+		if err != nil || again.Op != fr.Op || !bytes.Equal(again.Payload, fr.Payload) {
+			t.Errorf("Frame %+v did not round-trip: %v", fr, err)
+		}
+	})
+}
+
+func TestConnPeerClose(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	go func() {
+		server.Write([]byte{0x88, 0})
+	}()
+	c := NewConn(client, client, true)
+	echoed := make(chan Frame, 1)
+	go func() {
+		f, _ := ReadFrame(server)
+		echoed <- f
+	}()
+	if _, err := c.ReadMessage(); err != io.EOF {
+		t.Fatalf("Expected io.EOF after close, got %v", err)
+	}
+	if f := <-echoed; f.Op != OpClose {
+		t.Errorf("Expected the close frame to be echoed, got %#x", f.Op)
+	}
+
+	// The connection is released and refuses further writes
+	if err := c.WriteMessage(OpText, []byte("late")); err != io.ErrClosedPipe {
+		t.Errorf("Expected io.ErrClosedPipe, got %v", err)
+	}
+	if _, err := server.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Expected the underlying connection closed, got %v", err)
+	}
+}
+
+func TestCloseStalledPeer(t *testing.T) {
+	defer func(d time.Duration) { writeTimeout = d }(writeTimeout)
+	writeTimeout = 50 * time.Millisecond
+
+	// The peer never reads, so every write stalls
+	client, server := net.Pipe()
+	defer server.Close()
+	c := NewConn(client, client, true)
+	go c.WriteMessage(OpText, []byte("stuck"))
+
+	done := make(chan struct{})
+	go func() {
+		c.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Expected Close to return despite the stalled peer")
+	}
+}
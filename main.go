@@ -1,10 +1,15 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"time"
 
 	tea "charm.land/bubbletea/v2"
+	"github.com/wallacegibbon/proxy-controller-tui/internal/clash"
+	"github.com/wallacegibbon/proxy-controller-tui/internal/config"
 	"github.com/wallacegibbon/proxy-controller-tui/internal/tui"
 )
 
@@ -15,11 +20,59 @@ func main() {
 		}
 	}()
 
+	if len(os.Args) > 1 && os.Args[1] == "mock-server" {
+		if err := runMockServer(os.Args[2:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				os.Exit(0)
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	backend, err := newBackend(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
 	p := tea.NewProgram(
-		tui.InitialModel(),
+		tui.InitialModel(backend, tui.Options{
+			CloseConnectionsOnSwitch: cfg.CloseConnectionsOnSwitch,
+			MemoryThreshold:          int64(cfg.MemoryThreshold),
+		}),
 	)
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Error: %v", err)
 		os.Exit(1)
 	}
 }
+
+func newBackend(cfg config.Config) (clash.Backend, error) {
+	if cfg.Mock {
+		return clash.NewMockBackend(), nil
+	}
+	return clash.NewClient(clash.Options{
+		BaseURL:   cfg.Controller,
+		Secret:    cfg.Secret,
+		Timeout:   time.Duration(cfg.Timeout),
+		WebSocket: cfg.WebSocket,
+		TLS: clash.TLSOptions{
+			CAFile:             cfg.CAFile,
+			Fingerprint:        cfg.Fingerprint,
+			ClientCert:         cfg.ClientCert,
+			ClientKey:          cfg.ClientKey,
+			InsecureSkipVerify: cfg.InsecureSkipVerify,
+		},
+	})
+}
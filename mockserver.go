@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/wallacegibbon/proxy-controller-tui/internal/fakeclash"
+)
+
+// runMockServer serves the fake controller until interrupted, so the TUI
+// (or any other client) can be pointed at it.
+func runMockServer(args []string) error {
+	fs := flag.NewFlagSet("mock-server", flag.ContinueOnError)
+	listen := fs.String("listen", "127.0.0.1:9090", "address to listen on")
+	secret := fs.String("secret", "", "require this API secret")
+	latency := fs.Duration("latency", 0, "delay added to every response")
+	var faults []fakeclash.Fault
+	fs.Func("fail", "inject a fault, KIND[:PATH] with KIND one of 401, 404, 500, timeout, malformed (repeatable)", func(s string) error {
+		kind, path, _ := strings.Cut(s, ":")
+		k, ok := fakeclash.ParseFaultKind(kind)
+		if !ok {
+			return fmt.Errorf("unknown fault kind %q", kind)
+		}
+		faults = append(faults, fakeclash.Fault{Path: path, Kind: k})
+		return nil
+	})
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	fake := fakeclash.New(fakeclash.Options{Secret: *secret, Latency: *latency})
+	for _, f := range faults {
+		fake.Inject(f)
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		fake.ServeHTTP(w, r)
+		log.Printf("%s %s (%s)", r.Method, r.URL.RequestURI(), time.Since(start).Round(time.Millisecond))
+	})
+
+	log.SetOutput(os.Stderr)
+	log.Printf("mock controller listening on http://%s", *listen)
+	err := http.ListenAndServe(*listen, handler)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}